@@ -11,6 +11,7 @@ require (
 	github.com/go-co-op/gocron v1.36.0
 	github.com/integrii/flaggy v1.5.2
 	github.com/kgadams/go-shellquote v0.0.0-20220913102612-f87aa9739d7c
+	github.com/miekg/dns v1.1.56
 	github.com/prometheus-community/pro-bing v0.3.0
 	github.com/prometheus/client_golang v1.17.0
 	github.com/pseidemann/finish v1.2.0
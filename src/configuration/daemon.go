@@ -2,6 +2,10 @@ package configuration
 
 import (
 	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/creasty/defaults"
 	"os"
@@ -10,14 +14,77 @@ import (
 )
 
 type GlobalOptions struct {
-	DefaultSchedule    string `json:"default_schedule"`
-	BindTo             string `json:"bind_to" default:"localhost:9091"`
-	ExitOnConfigChange bool   `json:"exit_on_config_change" default:"false"`
+	DefaultSchedule      string         `json:"default_schedule"`
+	BindTo               string         `json:"bind_to" default:"localhost:9091"`
+	ExitOnConfigChange   bool           `json:"exit_on_config_change" default:"false"`
+	ReloadOnConfigChange bool           `json:"reload_on_config_change" default:"false"`
+	JobsAPI              bool           `json:"jobs_api" default:"false"`          // enables the API to manage jobs
+	JobsAPIPersist       bool           `json:"jobs_api_persist" default:"false"`  // saves jobs changed with the API to the config file
+	JobsAPIToken         string         `json:"jobs_api_token"`                    // bearer token required by the API, no default
+	JobsAPICommands      bool           `json:"jobs_api_commands" default:"false"` // allows cmd probes and hooks in the API jobs
+	History              HistoryOptions `json:"history"`
+	AvailabilityWindows  []string       `json:"availability_windows"` // rolling windows of the task success ratios, e.g. 1h, 30d
 }
 
 type DaemonConfig struct {
-	Global GlobalOptions
-	Jobs   []model.ScheduleJob
+	Global    GlobalOptions
+	Jobs      []model.ScheduleJob
+	Notifiers []NotifierOptions `json:"notifiers"`
+}
+
+// HistoryOptions is the history section of the daemon configuration
+type HistoryOptions struct {
+	Store      string `json:"store" default:"memory"` // memory or jsonl
+	File       string `json:"file"`                   // jsonl file
+	MaxRecords int    `json:"max_records" default:"100"`
+	MaxAge     string `json:"max_age"` // e.g. 168h
+}
+
+// NotifierOptions is a notifier of the daemon configuration, time values are in milliseconds
+type NotifierOptions struct {
+	Name       string        `json:"name"`
+	Type       string        `json:"type" default:"webhook"`
+	Jobs       []string      `json:"jobs"`                 // notify about these jobs only, all jobs if empty
+	Tasks      bool          `json:"tasks" default:"true"` // notify about task state changes as well as script ones
+	Failures   uint          `json:"failures" default:"1"` // consecutive failures to notify about a failure
+	Retries    uint          `json:"retries" default:"3"`  // additional delivery attempts
+	RetryDelay time.Duration `json:"retry_delay" default:"1000"`
+	Timeout    time.Duration `json:"timeout" default:"10000"` // delivery attempt timeout
+	Digest     time.Duration `json:"digest"`                  // events are sent in one message per interval if set
+	// webhook
+	URL     string            `json:"url"`
+	Method  string            `json:"method" default:"POST"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"` // text/template of the request body or the email with Event data
+	// smtp
+	Host          string              `json:"host"`
+	Port          int                 `json:"port"`                   // 587 for starttls, 465 for implicit and 25 for none by default
+	TLS           string              `json:"tls" default:"starttls"` // starttls, implicit or none
+	TLSSkipVerify bool                `json:"tls_skip_verify"`
+	Username      string              `json:"username"`
+	Password      string              `json:"password"`
+	From          string              `json:"from"`
+	To            []string            `json:"to"`             // recipients of all jobs
+	JobRecipients map[string][]string `json:"job_recipients"` // additional recipients by job name
+	Subject       string              `json:"subject"`        // text/template with Event data
+	DigestSubject string              `json:"digest_subject"` // text/template with Digest data
+	DigestBody    string              `json:"digest_body"`    // text/template with Digest data
+}
+
+func (o *NotifierOptions) UnmarshalJSON(b []byte) (err error) {
+	type tmp NotifierOptions
+	var t tmp
+	if err = defaults.Set(&t); err != nil {
+		return
+	}
+	if err = json.Unmarshal(b, &t); err != nil {
+		return
+	}
+	t.RetryDelay *= time.Millisecond
+	t.Timeout *= time.Millisecond
+	t.Digest *= time.Millisecond
+	*o = NotifierOptions(t)
+	return
 }
 
 func DaemonYMLConfiguration(data []byte) (config DaemonConfig, err error) {
@@ -28,22 +95,59 @@ func DaemonYMLConfiguration(data []byte) (config DaemonConfig, err error) {
 		return
 	}
 
+	if config.Global.JobsAPI && config.Global.JobsAPIToken == "" {
+		err = errors.New("jobs_api_token should be set to enable jobs_api")
+		return
+	}
+
+	names := make(map[string]bool, len(config.Jobs))
 	for i, j := range config.Jobs {
+		if names[j.Name] {
+			err = fmt.Errorf("job name %v isn't unique", j.Name)
+			return
+		}
+		names[j.Name] = true
 		var scriptData []byte
 		scriptData, err = os.ReadFile(j.ScriptFile)
 		if err != nil {
 			return
 		}
-		// job custom variables is defined
-		config.Jobs[i].Script, err = ScriptYMLConfiguration(scriptData, j.Vars)
-		if err != nil {
-			err = fmt.Errorf("can't parse configuration from %v: %w", j.ScriptFile, err)
+		if err = configureJob(&config.Jobs[i], scriptData, config.Global.DefaultSchedule); err != nil {
 			return
 		}
-		config.Jobs[i].Script.Timeout = time.Millisecond * j.Timeout
-		if config.Jobs[i].Schedule == "" {
-			config.Jobs[i].Schedule = config.Global.DefaultSchedule
+	}
+	return
+}
+
+// configureJob creates the job script from the script data and sets the job defaults
+func configureJob(j *model.ScheduleJob, scriptData []byte, defaultSchedule string) (err error) {
+	// job custom variables is defined
+	j.Script, err = ScriptYMLConfiguration(scriptData, j.Vars)
+	if err != nil {
+		return fmt.Errorf("can't parse configuration from %v: %w", j.ScriptFile, err)
+	}
+	j.Script.Timeout = time.Millisecond * j.Timeout
+	j.ScriptHash = fmt.Sprintf("%x", sha256.Sum256(scriptData))
+	if j.Schedule == "" {
+		j.Schedule = defaultSchedule
+	}
+	if err = j.Stability.Validate(); err != nil {
+		return fmt.Errorf("[%v] %w", j.Name, err)
+	}
+	for name, hook := range j.Hooks() {
+		// the command is checked the same way as the cmd probe one
+		if _, err = probefactory.NewProbe("cmd", model.DefaultProbeOptions, hook.Cmd); err != nil {
+			return fmt.Errorf("[%v] %v hook: %w", j.Name, name, err)
 		}
 	}
 	return
 }
+
+// DaemonYMLConfigurationFile reads and parses the daemon configuration file
+func DaemonYMLConfigurationFile(fileName string) (config DaemonConfig, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return
+	}
+	return DaemonYMLConfiguration(data)
+}
@@ -0,0 +1,226 @@
+package configuration
+
+import (
+	"boogieman/src/model"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"sync"
+)
+
+// commandProbes are the probes running commands on the host
+var commandProbes = map[string]bool{"cmd": true}
+
+// jobDefinition is a job of the daemon configuration with an optional inline script
+type jobDefinition struct {
+	model.ScheduleJob
+	// ScriptContent is the script configuration, it's saved to the ScriptFile
+	ScriptContent json.RawMessage `json:"scriptContent"`
+}
+
+// JobsConfigurator creates jobs from the jobs API requests and saves them to the daemon configuration file
+type JobsConfigurator struct {
+	ConfigFileName string
+	Global         GlobalOptions
+	scripts        map[string][]byte // inline scripts by file name waiting to be saved
+	sync.Mutex
+}
+
+func NewJobsConfigurator(configFileName string, global GlobalOptions) *JobsConfigurator {
+	return &JobsConfigurator{ConfigFileName: configFileName, Global: global, scripts: make(map[string][]byte)}
+}
+
+// ParseJob creates the job from the json or yaml job definition,
+// the definition is the same as a job of the daemon configuration with the optional scriptContent,
+// scriptContent is saved to <job name>.yml next to the configuration file,
+// script is a path relative to the directory of the configuration file, the job gets the path joined with the directory
+func (c *JobsConfigurator) ParseJob(name string, data []byte) (j model.ScheduleJob, err error) {
+	var d jobDefinition
+	if err = yaml.Unmarshal(data, &d); err != nil {
+		return
+	}
+	if d.Name != "" && d.Name != name {
+		err = fmt.Errorf("job name %v doesn't match %v", d.Name, name)
+		return
+	}
+	j = d.ScheduleJob
+	j.Name = name
+	j.Script, j.CronJob, j.ScriptHash = nil, nil, ""
+
+	var scriptData []byte
+	if len(d.ScriptContent) > 0 && string(d.ScriptContent) != "null" {
+		if scriptData, err = yaml.JSONToYAML(d.ScriptContent); err != nil {
+			return
+		}
+		scriptFile := filepath.Join(filepath.Dir(c.ConfigFileName), name+".yml")
+		if j.ScriptFile != "" && filepath.Clean(j.ScriptFile) != name+".yml" {
+			err = fmt.Errorf("script %v can't be set with scriptContent, it's saved to %v", j.ScriptFile, name+".yml")
+			return
+		}
+		j.ScriptFile = scriptFile
+	} else {
+		if j.ScriptFile == "" {
+			err = errors.New("either script or scriptContent should be defined")
+			return
+		}
+		if j.ScriptFile, err = c.scriptPath(j.ScriptFile); err != nil {
+			return
+		}
+		if scriptData, err = os.ReadFile(j.ScriptFile); err != nil {
+			return
+		}
+	}
+	if err = c.checkCommands(j, scriptData); err != nil {
+		return
+	}
+	if err = configureJob(&j, scriptData, c.Global.DefaultSchedule); err != nil {
+		return
+	}
+	if len(d.ScriptContent) > 0 && c.Global.JobsAPIPersist {
+		c.Lock()
+		c.scripts[j.ScriptFile] = scriptData
+		c.Unlock()
+	}
+	return
+}
+
+// scriptPath returns the path of the script relative to the directory of the configuration file,
+// the paths outside the directory are rejected, so the API can't read arbitrary files
+func (c *JobsConfigurator) scriptPath(fileName string) (string, error) {
+	if filepath.IsAbs(fileName) {
+		return "", fmt.Errorf("script %v should be a relative path", fileName)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(fileName), "/") {
+		if part == ".." {
+			return "", fmt.Errorf("script %v shouldn't contain ..", fileName)
+		}
+	}
+	dir := filepath.Dir(c.ConfigFileName)
+	path := filepath.Join(dir, fileName)
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("script %v should be a file inside %v", fileName, dir)
+	}
+	return path, nil
+}
+
+// checkCommands rejects the hooks and the command probes of the job unless jobs_api_commands is enabled,
+// so the API clients can't run commands on the host
+func (c *JobsConfigurator) checkCommands(j model.ScheduleJob, scriptData []byte) error {
+	if c.Global.JobsAPICommands {
+		return nil
+	}
+	for name := range j.Hooks() {
+		return fmt.Errorf("%v hook isn't allowed unless jobs_api_commands is enabled", name)
+	}
+	var parsed script
+	if err := yaml.Unmarshal(scriptData, &parsed); err != nil {
+		return err
+	}
+	for _, t := range parsed.Script {
+		if commandProbes[t.Probe.Name] {
+			return fmt.Errorf("[%v] %v probe isn't allowed unless jobs_api_commands is enabled", t.Name, t.Probe.Name)
+		}
+	}
+	return nil
+}
+
+// SaveJob replaces the job in the configuration file or removes it if j is nil, other jobs are kept as they are,
+// the inline script of the job is saved as well, nothing is saved unless jobs_api_persist is enabled
+func (c *JobsConfigurator) SaveJob(name string, j *model.ScheduleJob) (err error) {
+	if !c.Global.JobsAPIPersist {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	data, err := os.ReadFile(c.ConfigFileName)
+	if err != nil {
+		return
+	}
+	var config map[string]any
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return
+	}
+	if config == nil {
+		config = make(map[string]any)
+	}
+	configJobs, _ := config["jobs"].([]any)
+
+	if j != nil {
+		// the script is saved if the job is applied with it
+		if script, ok := c.scripts[j.ScriptFile]; ok && fmt.Sprintf("%x", sha256.Sum256(script)) == j.ScriptHash {
+			if err = writeFile(j.ScriptFile, script); err != nil {
+				return
+			}
+			delete(c.scripts, j.ScriptFile)
+		}
+	}
+
+	found := false
+	jobs := make([]any, 0, len(configJobs)+1)
+	for _, item := range configJobs {
+		if m, ok := item.(map[string]any); ok && m["name"] == name {
+			found = true
+			if j == nil {
+				continue
+			}
+			item = configJob(*j)
+		}
+		jobs = append(jobs, item)
+	}
+	if !found && j != nil {
+		jobs = append(jobs, configJob(*j))
+	}
+	config["jobs"] = jobs
+
+	if data, err = yaml.Marshal(config); err != nil {
+		return
+	}
+	return writeFile(c.ConfigFileName, data)
+}
+
+// configJob returns the job as it's defined in the daemon configuration
+func configJob(j model.ScheduleJob) map[string]any {
+	c := map[string]any{
+		"name":     j.Name,
+		"script":   j.ScriptFile,
+		"schedule": j.Schedule,
+	}
+	if j.Once {
+		c["once"] = true
+	}
+	if j.Paused {
+		c["paused"] = true
+	}
+	if j.Timeout != 0 {
+		c["timeout"] = int64(j.Timeout)
+	}
+	if len(j.Vars) > 0 {
+		c["vars"] = j.Vars
+	}
+	for name, hook := range j.Hooks() {
+		c[name] = hook
+	}
+	if j.Stability != nil {
+		c["stability"] = j.Stability
+	}
+	return c
+}
+
+// writeFile replaces the file at once, so the file watcher doesn't get a partially written file
+func writeFile(fileName string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := fileName + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fileName)
+}
@@ -15,10 +15,14 @@ type script struct {
 }
 
 type task struct {
-	Name   string
-	Probe  probe
-	CGroup string
-	Metric model.TaskMetric `json:"metric"`
+	Name      string
+	Probe     probe
+	CGroup    string
+	DependsOn []string         `json:"dependsOn"`
+	RunIf     string           `json:"runIf"`
+	SkipIf    string           `json:"skipIf"`
+	Metric    model.TaskMetric `json:"metric"`
+	SLO       float64          `json:"slo"`
 }
 
 type probe struct {
@@ -47,37 +51,82 @@ func ScriptYMLConfiguration(data []byte, overrideConfigOptions ...map[string]map
 	}
 	var p model.Prober
 	for _, t := range parsed.Script {
-		var config any
-		// get the probe configuration struct
-		config, err = probefactory.NewProbeConfiguration(t.Probe.Name)
+		var raw []byte
+		if t.Probe.RawConfiguration != nil {
+			raw = *t.Probe.RawConfiguration
+		}
+		if model.IsTemplate(raw) {
+			// the probe is created at run time with the configuration rendered from the results of other tasks
+			probeName, options, overrides := t.Probe.Name, t.Probe.Options, configOptions[t.Name]
+			if _, err = probefactory.NewProbeConfiguration(probeName); err != nil {
+				err = fmt.Errorf("[%v] %w", t.Name, err)
+				return
+			}
+			p, err = model.NewTemplateProbe(probeName, options, raw, func(configuration []byte) (model.Prober, error) {
+				return newProbe(probeName, options, configuration, overrides)
+			})
+		} else {
+			p, err = newProbe(t.Probe.Name, t.Probe.Options, raw, configOptions[t.Name])
+		}
 		if err != nil {
 			err = fmt.Errorf("[%v] %w", t.Name, err)
 			return
 		}
-		// try to fill probe config from RawConfiguration data
-		if t.Probe.RawConfiguration != nil {
-			if e := json.Unmarshal(*t.Probe.RawConfiguration, config); e == nil {
-				// override config properties if there are options in configOptions for this task (task name)
-				if o, ok := configOptions[t.Name]; ok {
-					for fName, fValue := range o {
-						if e = setStructField(config, fName, fValue); e != nil {
-							err = fmt.Errorf("[%v] %w", t.Name, e)
-							return
-						}
-					}
-				}
-			} else {
-				// if unmarshal error, set probe config to raw data in order the probe try to parse raw data by itself
-				config = []byte(*t.Probe.RawConfiguration)
-			}
+		task := model.NewTask(t.Name, t.CGroup, t.Metric, p)
+		task.DependsOn = t.DependsOn
+		if t.SLO < 0 || t.SLO >= 1 {
+			err = fmt.Errorf("[%v] slo should be a success ratio between 0 and 1, e.g. 0.999", t.Name)
+			return
 		}
-
-		p, err = probefactory.NewProbe(t.Probe.Name, t.Probe.Options, config)
-		if err != nil {
+		task.SLO = t.SLO
+		if err = t.Metric.Validate(); err != nil {
 			err = fmt.Errorf("[%v] %w", t.Name, err)
 			return
 		}
-		s.AddTask(model.NewTask(t.Name, t.CGroup, t.Metric, p))
+		if task.RunIf, err = taskCondition(t.RunIf); err != nil {
+			err = fmt.Errorf("[%v] runIf: %w", t.Name, err)
+			return
+		}
+		if task.SkipIf, err = taskCondition(t.SkipIf); err != nil {
+			err = fmt.Errorf("[%v] skipIf: %w", t.Name, err)
+			return
+		}
+		s.AddTask(task)
 	}
+	err = s.ResolveDependencies()
 	return
 }
+
+// newProbe creates the probe with the json configuration,
+// overrides are the values of the configuration properties to set by property names
+func newProbe(name string, options model.ProbeOptions, raw []byte, overrides map[string]string) (p model.Prober, err error) {
+	var config any
+	// get the probe configuration struct
+	config, err = probefactory.NewProbeConfiguration(name)
+	if err != nil {
+		return
+	}
+	// try to fill probe config from raw data
+	if raw != nil {
+		if e := json.Unmarshal(raw, config); e == nil {
+			// override config properties if there are options for this task
+			for fName, fValue := range overrides {
+				if err = setStructField(config, fName, fValue); err != nil {
+					return
+				}
+			}
+		} else {
+			// if unmarshal error, set probe config to raw data in order the probe try to parse raw data by itself
+			config = raw
+		}
+	}
+	return probefactory.NewProbe(name, options, config)
+}
+
+// taskCondition parses the task condition expression, an empty expression means no condition
+func taskCondition(expression string) (*model.Condition, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	return model.NewCondition(expression)
+}
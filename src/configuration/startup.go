@@ -30,6 +30,7 @@ type startupOptions struct {
 	ProbeConf           string
 	ProbeOptionsTimeout time.Duration
 	ProbeOptionsExpect  bool `envconfig:"default=true"`
+	ScriptTimeout       time.Duration
 	Debug               bool
 	VerboseLog          bool
 	//Config              string
@@ -42,6 +43,7 @@ type StartupConfig struct {
 	Script         *model.Script
 	ScheduleJobs   []model.ScheduleJob
 	ConfigFileName string
+	Notifiers      []NotifierOptions
 	GlobalOptions
 }
 
@@ -65,6 +67,7 @@ func StartupConfiguration() (config StartupConfig, err error) {
 	oneRun.String(&o.Probe, "p", "probe", "single probe to start (ignored if script option is selected)")
 	oneRun.String(&o.ProbeConf, "c", "config", "probe configuration string (ignored if script option is selected)")
 	oneRun.Duration(&o.ProbeOptionsTimeout, "t", "timeout", "probe waiting timeout (ignored if script option is selected)")
+	oneRun.Duration(&o.ScriptTimeout, "", "script-timeout", "the whole script execution timeout")
 	oneRun.Bool(&o.Debug, "d", "debug", "debug logging")
 	oneRun.Bool(&o.VerboseLog, "v", "verbose", "verbose logging")
 	oneRun.Bool(&o.ProbeOptionsExpect, "e", "expect", "expected result true|false (ignored if script option is selected)")
@@ -126,6 +129,7 @@ func StartupConfiguration() (config StartupConfig, err error) {
 				return
 			}
 		}
+		config.Script.Timeout = o.ScriptTimeout
 		return
 	case daemon.Used:
 		config.Mode = StartupModeDaemon
@@ -134,18 +138,14 @@ func StartupConfiguration() (config StartupConfig, err error) {
 			flaggy.ShowHelp(err.Error())
 			return
 		}
-		var data []byte
-		data, err = os.ReadFile(config.ConfigFileName)
-		if err != nil {
-			return
-		}
-		daemonConfig, e := DaemonYMLConfiguration(data)
+		daemonConfig, e := DaemonYMLConfigurationFile(config.ConfigFileName)
 		if e != nil {
 			err = fmt.Errorf("can't parse configuration from file: %w", e)
 			return
 		}
 		config.GlobalOptions = daemonConfig.Global
 		config.ScheduleJobs = daemonConfig.Jobs
+		config.Notifiers = daemonConfig.Notifiers
 	default:
 		flaggy.ShowHelp("")
 		return
@@ -3,6 +3,8 @@ package main
 import (
 	"boogieman/src/configuration"
 	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"boogieman/src/services/notifier"
 	"boogieman/src/services/prometheus"
 	"boogieman/src/services/scheduler"
 	"boogieman/src/services/webserver"
@@ -14,7 +16,10 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"reflect"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -27,6 +32,7 @@ const (
 
 const (
 	ShutdownWaitingTimeout = 30 * time.Second
+	ConfigReloadDelay      = 500 * time.Millisecond
 )
 
 var gitTag, gitCommit, gitBranch, buildTimestamp string
@@ -57,8 +63,41 @@ func main() {
 	}
 
 	// daemon mode
+	historyStore, err := history.New(history.Options(config.History))
+	if err != nil {
+		fmt.Printf("Wrong history configuration: %v\n", err)
+		os.Exit(ExitErrConfig)
+	}
+	notifierOptions := make([]notifier.Options, 0, len(config.Notifiers))
+	for _, o := range config.Notifiers {
+		notifierOptions = append(notifierOptions, notifier.Options(o))
+	}
+	notifiers, err := notifier.New(notifierOptions)
+	if err != nil {
+		fmt.Printf("Wrong notifiers configuration: %v\n", err)
+		os.Exit(ExitErrConfig)
+	}
 	schedulerService := scheduler.Run()
+	schedulerService.SetHistory(historyStore)
+	if len(config.AvailabilityWindows) > 0 {
+		windows, err := scheduler.ParseAvailabilityWindows(config.AvailabilityWindows)
+		if err != nil {
+			fmt.Printf("Wrong availability windows: %v\n", err)
+			os.Exit(ExitErrConfig)
+		}
+		schedulerService.SetAvailabilityWindows(windows)
+	}
+	schedulerService.SetNotifier(notifiers)
 	finisher.Add(schedulerService, finish.WithName("scheduler"))
+	finisher.Add(notifiers, finish.WithName("notifiers"))
+
+	if config.JobsAPI {
+		configurator := configuration.NewJobsConfigurator(config.ConfigFileName, config.GlobalOptions)
+		if err = schedulerService.SetConfigurator(configurator, config.JobsAPIToken); err != nil {
+			fmt.Printf("Wrong jobs API configuration: %v\n", err)
+			os.Exit(ExitErrConfig)
+		}
+	}
 
 	// prometheus
 	prometheusService := prometheus.Run(true, true, schedulerService)
@@ -77,12 +116,17 @@ func main() {
 		}
 	}
 
-	if config.ExitOnConfigChange {
-		watcher, err := util.Watcher(
+	var watcher *util.FileWatcher
+	if config.ExitOnConfigChange || config.ReloadOnConfigChange {
+		watcher, err = util.Watcher(
 			[]string{config.ConfigFileName},
 			func(path, op string) {
-				// exit
-				_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+				if config.ExitOnConfigChange {
+					// exit
+					_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+					return
+				}
+				reloadConfiguration(config, schedulerService, watcher)
 			})
 		if err != nil {
 			log.Printf("error with creating a file watcher: %v\n", err)
@@ -94,10 +138,62 @@ func main() {
 		}
 	}
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfiguration(config, schedulerService, watcher)
+		}
+	}()
+
 	finisher.Wait()
 	os.Exit(ExitOk)
 }
 
+var (
+	reloadLock  sync.Mutex
+	reloadTimer *time.Timer
+)
+
+// reloadConfiguration re-reads the daemon configuration and applies changed jobs to the scheduler,
+// the reload is delayed by ConfigReloadDelay in order to handle a series of file changes at once
+func reloadConfiguration(config configuration.StartupConfig, s *scheduler.Scheduler, watcher *util.FileWatcher) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	if reloadTimer != nil {
+		reloadTimer.Stop()
+	}
+	reloadTimer = time.AfterFunc(ConfigReloadDelay, func() {
+		reloadLock.Lock()
+		defer reloadLock.Unlock()
+		logger := model.NewChainLogger(model.DefaultLogger, "configuration")
+		logger.Printf("reloading from %v\n", config.ConfigFileName)
+		daemonConfig, err := configuration.DaemonYMLConfigurationFile(config.ConfigFileName)
+		if err != nil {
+			logger.Printf("isn't applied: %v\n", err)
+			return
+		}
+		if !reflect.DeepEqual(daemonConfig.Global, config.GlobalOptions) {
+			logger.Println("global options changes are applied after restart only")
+		}
+		if !reflect.DeepEqual(daemonConfig.Notifiers, config.Notifiers) {
+			logger.Println("notifiers changes are applied after restart only")
+		}
+		if err = s.ApplyJobs(daemonConfig.Jobs); err != nil {
+			logger.Printf("isn't applied: %v\n", err)
+			return
+		}
+		if watcher != nil {
+			// files replaced by editors should be watched again
+			_ = watcher.Add(config.ConfigFileName)
+			for _, j := range daemonConfig.Jobs {
+				_ = watcher.Add(j.ScriptFile)
+			}
+		}
+		logger.Println("has been reloaded")
+	})
+}
+
 func runScriptAndExit(config configuration.StartupConfig) {
 	ctx := context.Background()
 	config.JSON = config.JSON || config.PrettyJSON
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+var ErrCondition = errors.New("wrong condition")
+
+// Condition is a text/template pipeline over the results of the script tasks, e.g.
+// and (not (index .tasks "gateway-alive" "success")) (ne .tasks.vpn.status "skipped")
+//
+// The task fields are the same as in the probe configuration templates,
+// the condition is true if the pipeline value is true in the sense of the template if action
+type Condition struct {
+	Source string
+	tmpl   *template.Template
+	tasks  []string
+}
+
+// NewCondition parses the pipeline
+func NewCondition(source string) (c *Condition, err error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("%w %q: empty expression", ErrCondition, source)
+	}
+	t, err := template.New("condition").Parse("{{ if " + source + " }}true{{ end }}")
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrCondition, source, err)
+	}
+	return &Condition{Source: source, tmpl: t, tasks: templateTasks(t.Tree.Root)}, nil
+}
+
+// Tasks returns the names of the tasks referenced by the condition
+func (c *Condition) Tasks() []string {
+	return c.tasks
+}
+
+// Eval evaluates the condition with the task results, missing fields are empty values
+func (c *Condition) Eval(tasks map[string]any) (bool, error) {
+	var b strings.Builder
+	if err := c.tmpl.Execute(&b, map[string]any{"tasks": tasks}); err != nil {
+		return false, err
+	}
+	return b.String() == "true", nil
+}
+
+// ConditionTaskVars returns the task result representation available to conditions and templates
+func ConditionTaskVars(r TaskResult) map[string]any {
+	var data any
+	if r.Probe.Data != nil {
+		if b, err := json.Marshal(r.Probe.Data); err == nil {
+			_ = json.Unmarshal(b, &data)
+		}
+	}
+	return map[string]any{
+		"success": r.Success,
+		"status":  r.Status,
+		"reason":  r.Reason,
+		"runtime": r.RuntimeMs,
+		"data":    data,
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Condition(t *testing.T) {
+	tasks := map[string]any{
+		"gateway-alive": map[string]any{"success": false, "status": "finished", "runtime": 120, "data": nil},
+		"web": map[string]any{
+			"success": true,
+			"status":  "finished",
+			"data": map[string]any{
+				"httpStatus": map[string]any{"https://example.com/": float64(200)},
+				"items":      []any{"a", "b"},
+			},
+		},
+	}
+
+	type testCase struct {
+		expression string
+		expected   bool
+	}
+	cases := []testCase{
+		{`not (index .tasks "gateway-alive" "success")`, true},
+		{`eq (index .tasks "gateway-alive" "success") false`, true},
+		{`index .tasks "gateway-alive" "success"`, false},
+		{`and (eq .tasks.web.status "finished") .tasks.web.success`, true},
+		{`or (ne .tasks.web.status "finished") (ge (index .tasks "gateway-alive" "runtime") 100)`, true},
+		{`eq (index .tasks.web.data.httpStatus "https://example.com/") 200.0`, true},
+		{`and (lt (index .tasks.web.data.httpStatus "https://example.com/") 300.0) (eq (index .tasks.web.data.items 1) "b")`, true},
+		{".tasks.web.data.missing", false},
+		{"not .tasks.web.data.missing", true},
+		{".tasks.web.data.items", true},
+	}
+	for _, c := range cases {
+		t.Run(c.expression, func(t *testing.T) {
+			cond, err := NewCondition(c.expression)
+			if err != nil {
+				t.Fatalf("condition should be parsed: %v", err)
+			}
+			r, err := cond.Eval(tasks)
+			if err != nil {
+				t.Fatalf("condition should be evaluated: %v", err)
+			}
+			if r != c.expected {
+				t.Fatalf("condition should be %v", c.expected)
+			}
+		})
+	}
+
+	// errors of the evaluation are returned
+	for _, e := range []string{`gt .tasks.web.status 1`, `index .tasks "gateway-alive" "data" "field"`} {
+		cond, err := NewCondition(e)
+		if err != nil {
+			t.Fatalf("condition %q should be parsed: %v", e, err)
+		}
+		if _, err = cond.Eval(tasks); err == nil {
+			t.Errorf("condition %q should return an evaluation error", e)
+		}
+	}
+
+	wrongExpressions := []string{
+		"",
+		" ",
+		"{{ .tasks.web.success }}",
+		"(.tasks.web.success",
+		`eq .tasks.web.status "finished`,
+		".tasks.web.success = true",
+		"unknown .tasks.web.success",
+	}
+	for _, e := range wrongExpressions {
+		if _, err := NewCondition(e); !errors.Is(err, ErrCondition) {
+			t.Errorf("condition %q should return an error, got %v", e, err)
+		}
+	}
+
+	cond, _ := NewCondition(`and .tasks.a.success (eq (index .tasks "b-1" "status") "finished")`)
+	if names := cond.Tasks(); len(names) != 2 || names[0] != "a" || names[1] != "b-1" {
+		t.Fatalf("referenced tasks should be returned, got %v", names)
+	}
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	HookOnFailure  = "onFailure"
+	HookOnRecovery = "onRecovery"
+	HookOnEveryRun = "onEveryRun"
+
+	DefaultHookTimeout = 30 * time.Second
+)
+
+// JobHook is a command started after the job run, time values are in milliseconds
+type JobHook struct {
+	Cmd      string        `json:"cmd"`
+	Timeout  time.Duration `json:"timeout,omitempty"`  // the command is stopped after the timeout, 30s by default
+	Cooldown time.Duration `json:"cooldown,omitempty"` // the command isn't started again within the cooldown
+	last     HookResult
+	runs     uint
+	running  bool
+	lock     sync.Mutex
+}
+
+// HookResult is the result of the hook command
+type HookResult struct {
+	Hook      string    `json:"hook"`
+	StartedAt time.Time `json:"startedAt"`
+	RuntimeMs int       `json:"runtime"`
+	ExitCode  int       `json:"exitCode"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SameAs returns true if the hooks have the same configuration
+func (h *JobHook) SameAs(o *JobHook) bool {
+	if h == nil || o == nil {
+		return h == o
+	}
+	return h.Cmd == o.Cmd && h.Timeout == o.Timeout && h.Cooldown == o.Cooldown
+}
+
+// RunTimeout returns the command timeout
+func (h *JobHook) RunTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHookTimeout
+	}
+	return h.Timeout * time.Millisecond
+}
+
+// Start returns false if the previous command is still running or has been started within the cooldown,
+// otherwise the start is counted
+func (h *JobHook) Start(now time.Time) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.running || h.runs > 0 && h.Cooldown > 0 && now.Sub(h.last.StartedAt) < h.Cooldown*time.Millisecond {
+		return false
+	}
+	h.runs++
+	h.running = true
+	h.last = HookResult{StartedAt: now}
+	return true
+}
+
+// SetResult saves the result of the last command, the command is finished
+func (h *JobHook) SetResult(r HookResult) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.last = r
+	h.running = false
+}
+
+// LastResult returns the result of the last command and the number of the started commands
+func (h *JobHook) LastResult() (r HookResult, runs uint) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.last, h.runs
+}
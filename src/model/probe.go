@@ -19,7 +19,8 @@ type ProbeResult struct {
 	Options       ProbeOptions `json:"options"`
 	Configuration any          `json:"configuration"`
 	Result
-	Data any `json:"data"` // store last the result of the probing, the data type depends on probe
+	Errors map[string]ProbeError `json:"errors,omitempty"` // the failure causes by probe targets
+	Data   any                   `json:"data"`             // store last the result of the probing, the data type depends on probe
 }
 
 type Prober interface {
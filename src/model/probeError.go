@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// ErrorReason is the failure cause of a probe target
+type ErrorReason string
+
+const (
+	ReasonTimeout           ErrorReason = "timeout"
+	ReasonCanceled          ErrorReason = "canceled"
+	ReasonDNS               ErrorReason = "dns"
+	ReasonConnRefused       ErrorReason = "connection_refused"
+	ReasonWrongStatus       ErrorReason = "wrong_status"
+	ReasonRegexMismatch     ErrorReason = "regex_mismatch"
+	ReasonPermissionDenied  ErrorReason = "permission_denied"
+	ReasonExitCode          ErrorReason = "exit_code"
+	ReasonUnexpectedExit    ErrorReason = "unexpected_exit"
+	ReasonUnexpectedSuccess ErrorReason = "unexpected_success"
+	ReasonMismatch          ErrorReason = "mismatch"
+	ReasonCertificate       ErrorReason = "certificate"
+	ReasonRedirect          ErrorReason = "redirect"
+	ReasonConfig            ErrorReason = "config"
+	ReasonUnknown           ErrorReason = "unknown"
+)
+
+// ErrUnexpectedSuccess is the target error of a successful probing when a failure is expected
+var ErrUnexpectedSuccess = NewReasonError(ReasonUnexpectedSuccess, errors.New("unexpected success"))
+
+// ProbeError is the classified error of a probe target
+type ProbeError struct {
+	Reason  ErrorReason `json:"reason"`
+	Message string      `json:"message"`
+}
+
+// ReasonError is an error with the known failure reason
+type ReasonError struct {
+	Reason ErrorReason
+	Err    error
+}
+
+// NewReasonError wraps the error with the failure reason
+func NewReasonError(reason ErrorReason, err error) error {
+	return &ReasonError{Reason: reason, Err: err}
+}
+
+func (e *ReasonError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReasonError) Unwrap() error {
+	return e.Err
+}
+
+// NewProbeError classifies the error
+func NewProbeError(err error) ProbeError {
+	return ProbeError{Reason: ErrorReasonOf(err), Message: err.Error()}
+}
+
+// ErrorReasonOf returns the failure reason of the error, the reason set by NewReasonError takes precedence,
+// the errors of the standard library are recognized by their types, other ones by their messages
+func ErrorReasonOf(err error) ErrorReason {
+	var (
+		reasonErr *ReasonError
+		dnsErr    *net.DNSError
+		netErr    net.Error
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &reasonErr):
+		return reasonErr.Reason
+	case errors.Is(err, ErrorConfig):
+		return ReasonConfig
+	case errors.As(err, &dnsErr):
+		return ReasonDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonTimeout
+	case errors.Is(err, context.Canceled):
+		return ReasonCanceled
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ReasonConnRefused
+	case errors.Is(err, os.ErrPermission), errors.Is(err, syscall.EPERM):
+		return ReasonPermissionDenied
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "deadline exceeded"):
+		return ReasonTimeout
+	case strings.Contains(msg, "no such host"), strings.Contains(msg, "name resolution"):
+		return ReasonDNS
+	case strings.Contains(msg, "connection refused"):
+		return ReasonConnRefused
+	case strings.Contains(msg, "permission denied"), strings.Contains(msg, "not permitted"):
+		return ReasonPermissionDenied
+	}
+	return ReasonUnknown
+}
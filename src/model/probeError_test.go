@@ -0,0 +1,38 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test_ErrorReasonOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorReason
+	}{
+		{"nil", nil, ""},
+		{"reason error", fmt.Errorf("wrapped: %w", NewReasonError(ReasonWrongStatus, errors.New("wrong response 500"))), ReasonWrongStatus},
+		{"config", fmt.Errorf("%w: no urls", ErrorConfig), ReasonConfig},
+		{"dns", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "a.invalid", IsNotFound: true}}, ReasonDNS},
+		{"deadline", fmt.Errorf("http error %w", context.DeadlineExceeded), ReasonTimeout},
+		{"canceled", context.Canceled, ReasonCanceled},
+		{"connection refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ReasonConnRefused},
+		{"permission", &os.PathError{Op: "open", Path: "/root", Err: syscall.EACCES}, ReasonPermissionDenied},
+		{"operation not permitted", errors.New("socket: operation not permitted"), ReasonPermissionDenied},
+		{"timeout message", errors.New("timeout"), ReasonTimeout},
+		{"unknown", errors.New("something went wrong"), ReasonUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorReasonOf(tt.err); got != tt.want {
+				t.Errorf("ErrorReasonOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,13 +10,15 @@ type ProbeHandler struct {
 	Worker                   // describes status o probe working process
 	Name              string // probe name
 	Config            any
-	CanStayBackground bool          `json:"-"` // flag that means the probing process can stay in background
-	lastResult        Result        // represents the last probe running Result
-	curResult         Result        // represents the current probe running Result
-	probingData       any           // a specific probe implementation saves a lastResult object here
-	runner            ProbeRunner   // probe runner func
-	finisher          ProbeFinisher // probe finisher func, only for probe that stays alive in background
-	error             error         // last startup error
+	CanStayBackground bool                  `json:"-"` // flag that means the probing process can stay in background
+	lastResult        Result                // represents the last probe running Result
+	curResult         Result                // represents the current probe running Result
+	probingData       any                   // a specific probe implementation saves a lastResult object here
+	lastErrors        map[string]ProbeError // the target errors of the last probe running
+	curErrors         map[string]ProbeError // the target errors of the current probe running
+	runner            ProbeRunner           // probe runner func
+	finisher          ProbeFinisher         // probe finisher func, only for probe that stays alive in background
+	error             error                 // last startup error
 	logger            Logger
 }
 
@@ -31,6 +33,10 @@ func (c *ProbeHandler) Start(ctx context.Context) (succ bool) {
 	}
 
 	c.curResult.PrepareToStart()
+	c.error = nil
+	c.Lock()
+	c.curErrors = nil
+	c.Unlock()
 	c.logger = GetLogger(ctx)
 	c.logDebug("Starting the probe runner")
 
@@ -45,6 +51,7 @@ func (c *ProbeHandler) Start(ctx context.Context) (succ bool) {
 		c.Lock()
 		c.curResult.End(succ)
 		c.lastResult = c.curResult
+		c.lastErrors = c.curErrors
 		c.probingData = probingData
 		c.Unlock()
 
@@ -82,6 +89,21 @@ func (c *ProbeHandler) SetError(err error) *ProbeHandler {
 	return c
 }
 
+// SetTargetError classifies and keeps the error of the probe target in the result,
+// should be called internally from the probe, it's safe for concurrent use
+func (c *ProbeHandler) SetTargetError(target string, err error) *ProbeHandler {
+	if err == nil {
+		return c
+	}
+	c.Lock()
+	if c.curErrors == nil {
+		c.curErrors = make(map[string]ProbeError)
+	}
+	c.curErrors[target] = NewProbeError(err)
+	c.Unlock()
+	return c
+}
+
 // SetRunner sets the probe runner, should be set from the probe on init
 func (c *ProbeHandler) SetRunner(r ProbeRunner) *ProbeHandler {
 	c.runner = r
@@ -124,6 +146,7 @@ func (c *ProbeHandler) Result() (r ProbeResult) {
 	r.Options = c.ProbeOptions
 	if c.curResult.Completed() {
 		r.Result = c.lastResult
+		r.Errors = c.lastErrors
 		r.Data = c.probingData
 	} else {
 		r.Result = c.curResult
@@ -138,6 +161,7 @@ func (c *ProbeHandler) ResultFinished() (r ProbeResult) {
 	r.Name = c.Name
 	r.Options = c.ProbeOptions
 	r.Result = c.lastResult
+	r.Errors = c.lastErrors
 	r.Data = c.probingData
 	r.Configuration = c.Config
 	c.Unlock()
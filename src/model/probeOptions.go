@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"github.com/creasty/defaults"
+	"math"
 	"time"
 )
 
@@ -18,6 +19,9 @@ type ProbeOptions struct {
 	Expect         bool          `json:"expect" default:"true"`
 	Debug          bool          `json:"debug,omitempty" default:"false"`
 	VerboseLogging bool          `json:"-" default:"false"`
+	Retries        uint          `json:"retries,omitempty"`      // number of additional attempts if the probe fails
+	RetryDelay     time.Duration `json:"retryDelay,omitempty"`   // delay before the first retry
+	RetryBackoff   float64       `json:"retryBackoff,omitempty"` // multiplier of the delay for every next retry
 }
 
 func (s *ProbeOptions) UnmarshalJSON(b []byte) (err error) {
@@ -32,6 +36,7 @@ func (s *ProbeOptions) UnmarshalJSON(b []byte) (err error) {
 		return
 	}
 	t.Timeout *= time.Millisecond
+	t.RetryDelay *= time.Millisecond
 	*s = ProbeOptions(t)
 
 	return
@@ -42,5 +47,15 @@ func (s *ProbeOptions) MarshalJSON() ([]byte, error) {
 	type Options ProbeOptions
 	o := Options(*s)
 	o.Timeout = time.Duration(o.Timeout.Milliseconds())
+	o.RetryDelay = time.Duration(o.RetryDelay.Milliseconds())
 	return json.Marshal(&o)
 }
+
+// RetryDelayFor returns the delay before the retry with the number starting from 1
+func (s *ProbeOptions) RetryDelayFor(retry uint) time.Duration {
+	delay := float64(s.RetryDelay)
+	if s.RetryBackoff > 1 {
+		delay *= math.Pow(s.RetryBackoff, float64(retry-1))
+	}
+	return time.Duration(delay)
+}
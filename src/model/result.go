@@ -9,11 +9,13 @@ type Result struct {
 	Runtime    time.Duration `json:"-"`
 	RuntimeMs  int           `json:"runtime"`
 	Success    bool          `json:"success"`
-	RunCounter uint          `json:"runCounter"` // run counter
+	RunCounter uint          `json:"runCounter"`       // run counter
+	Reason     string        `json:"reason,omitempty"` // why the run was skipped
 }
 
 func (r *Result) PrepareToStart() {
 	r.Success = false
+	r.Reason = ""
 	r.StartedAt = time.Now()
 	r.Runtime = 0
 	r.RuntimeMs = 0
@@ -29,3 +31,10 @@ func (r *Result) End(succ bool) {
 func (r *Result) Completed() bool {
 	return r.Runtime != 0
 }
+
+// StateTimes are the times of the last runs, they're kept by the scheduler
+type StateTimes struct {
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastChangeAt  *time.Time `json:"lastChangeAt,omitempty"` // the result differs from the previous one, or the first run
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/go-co-op/gocron"
+	"reflect"
 	"time"
 )
 
@@ -10,9 +11,43 @@ type ScheduleJob struct {
 	ScriptFile  string        `json:"script"`
 	Schedule    string        `json:"schedule"`
 	Once        bool          `json:"once"`
+	Paused      bool          `json:"paused"` // the job isn't scheduled, but can be started manually
 	Timeout     time.Duration `json:"timeout"`
 	NextStartAt time.Time     `json:"nextStartAt"` // exclusively for JSON export
+	*StateTimes               // exclusively for JSON export
 	Script      *Script       `json:"-"`
 	CronJob     *gocron.Job   `json:"-"`
 	Vars        map[string]map[string]string
+	ScriptHash  string     `json:"-"`                    // hash of the script file content the Script is created from
+	OnFailure   *JobHook   `json:"onFailure,omitempty"`  // started when the job fails after success
+	OnRecovery  *JobHook   `json:"onRecovery,omitempty"` // started when the job succeeds after failure
+	OnEveryRun  *JobHook   `json:"onEveryRun,omitempty"` // started after every run
+	Stability   *Stability `json:"stability,omitempty"`  // thresholds of the stable task state and flapping
+}
+
+// Hooks returns the defined hooks by name
+func (j *ScheduleJob) Hooks() map[string]*JobHook {
+	hooks := make(map[string]*JobHook, 3)
+	for name, h := range map[string]*JobHook{HookOnFailure: j.OnFailure, HookOnRecovery: j.OnRecovery, HookOnEveryRun: j.OnEveryRun} {
+		if h != nil {
+			hooks[name] = h
+		}
+	}
+	return hooks
+}
+
+// SameAs returns true if the job has the same configuration and the same script content as the other job
+func (j *ScheduleJob) SameAs(o ScheduleJob) bool {
+	return j.Name == o.Name &&
+		j.ScriptFile == o.ScriptFile &&
+		j.Schedule == o.Schedule &&
+		j.Once == o.Once &&
+		j.Paused == o.Paused &&
+		j.Timeout == o.Timeout &&
+		j.ScriptHash == o.ScriptHash &&
+		reflect.DeepEqual(j.Vars, o.Vars) &&
+		j.OnFailure.SameAs(o.OnFailure) &&
+		j.OnRecovery.SameAs(o.OnRecovery) &&
+		j.OnEveryRun.SameAs(o.OnEveryRun) &&
+		j.Stability.SameAs(o.Stability)
 }
@@ -2,8 +2,11 @@ package model
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/enriquebris/goconcurrentqueue"
 	"github.com/starshiptroopers/uidgenerator"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,6 +24,11 @@ func init() {
 	)
 }
 
+// TimeoutGracePeriod is the time to wait for interrupted tasks after the script timeout is happened
+var TimeoutGracePeriod = time.Second
+
+var ErrDependency = errors.New("wrong task dependency")
+
 type Script struct {
 	Timeout time.Duration `json:"-"`
 	Tasks   []*Task
@@ -29,52 +37,182 @@ type Script struct {
 	anonymousCGroup   *CGroup
 	probesStayedAlive *goconcurrentqueue.FIFO
 	logger            Logger
+	timeouts          uint // number of runs interrupted by timeout
+	resolved          bool // task dependencies are resolved
 }
 
 type ScriptResult struct {
-	Result `json:"result"`
-	Status string       `json:"status"`
-	Tasks  []TaskResult `json:"tasks"`
+	Result      `json:"result"`
+	Status      string       `json:"status"`
+	Timeouts    uint         `json:"timeouts"`
+	Tasks       []TaskResult `json:"tasks"`
+	Hooks       []HookResult `json:"hooks,omitempty"` // job hooks finished after the run, set by the scheduler only
+	*StateTimes              // set by the scheduler only
 }
 
-// Run starts the script and blocks until finish
+// Run starts the script and blocks until finish or Timeout is happened,
+// tasks are started as soon as the tasks they depend on are finished
 func (s *Script) Run(ctx context.Context) {
 	if s.probesStayedAlive == nil {
 		s.probesStayedAlive = goconcurrentqueue.NewFIFO()
 	}
 	s.logger = GetLogger(ctx)
+	if !s.resolved {
+		if err := s.ResolveDependencies(); err != nil {
+			NewChainLogger(s.logger, "script").Println(err.Error())
+			return
+		}
+	}
 	if err := s.EStatusRun(); err != nil {
 		NewChainLogger(s.logger, "script").Println(err.Error())
 		return
 	}
 
-	for _, cGroup := range s.CGroups {
-		s.runCgroup(ctx, cGroup)
+	runCtx := ctx
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	for _, cgroup := range s.CGroups {
+		_ = cgroup.EStatusRun()
+	}
+
+	// a task channel is closed when the task is finished, skipped or interrupted
+	done := make(map[*Task]chan struct{}, len(s.Tasks))
+	for _, task := range s.Tasks {
+		done[task] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	for _, task := range s.Tasks {
+		wg.Add(1)
+		go func(task *Task) {
+			defer wg.Done()
+			defer close(done[task])
+			s.runTask(runCtx, task, done)
+		}(task)
+	}
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+	select {
+	case <-allDone:
+	case <-runCtx.Done():
+		// interrupt tasks that are still running and give them a chance to stop,
+		// tasks that haven't been started are interrupted by themselves
+		for _, task := range s.Tasks {
+			task.EStatusTimeout()
+		}
 		select {
-		case <-ctx.Done():
-		default:
+		case <-allDone:
+		case <-time.After(TimeoutGracePeriod):
+			NewChainLogger(s.logger, "script").Println("some tasks weren't stopped in time and are left behind")
 		}
 	}
 
 	// finished
-	succ := true
-	for _, t := range s.Tasks {
-		taskResult, _ := t.Worker.Result()
-		succ = succ && taskResult.Success
+	for _, cgroup := range s.CGroups {
+		if runCtx.Err() != nil {
+			cgroup.EStatusTimeout()
+		} else {
+			_ = cgroup.EStatusFinish(tasksSucceeded(cgroup.Tasks))
+		}
+	}
+	if runCtx.Err() != nil {
+		NewChainLogger(s.logger, "script").Printf("interrupted: %v\n", runCtx.Err())
+		s.Lock()
+		s.timeouts++
+		s.Unlock()
+		s.EStatusTimeout()
+	} else {
+		_ = s.EStatusFinish(tasksSucceeded(s.Tasks))
 	}
 
-	_ = s.EStatusFinish(succ)
-
-	// finishing background probes stayed alive
+	// finishing background probes stayed alive,
+	// the parent context is used as the script context can be already expired
 	for i, e := s.probesStayedAlive.Dequeue(); e == nil; i, e = s.probesStayedAlive.Dequeue() {
 		probe, ok := i.(Prober)
 		if !ok {
 			continue
 		}
-		probe.Finish(ctx)
+		probe.Finish(context.WithoutCancel(ctx))
 	}
 }
 
+// runTask waits for the task dependencies and starts the task,
+// the task is skipped if any of the tasks it depends on hasn't succeeded or its conditions don't allow to start it
+func (s *Script) runTask(ctx context.Context, task *Task, done map[*Task]chan struct{}) {
+	for _, waitFor := range [][]*Task{task.after, task.dependencies} {
+		for _, t := range waitFor {
+			select {
+			case <-done[t]:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		if task.EStatusRun() == nil {
+			task.setAttempts(nil, true)
+			task.EStatusTimeout()
+		}
+		return
+	}
+
+	for _, t := range task.dependencies {
+		r, status := t.Worker.Result()
+		if status == EStatusFinished && r.Success {
+			continue
+		}
+		reason := fmt.Sprintf("dependency %v has failed", t.Name)
+		if status != EStatusFinished {
+			reason = fmt.Sprintf("dependency %v is %v", t.Name, status)
+		}
+		task.setAttempts(nil, true)
+		if err := task.EStatusSkip(reason); err == nil {
+			NewChainLogger(s.logger, task.Name).Printf("skipped: %v\n", reason)
+		}
+		return
+	}
+
+	if reason := task.conditionsSkipReason(); reason != "" {
+		task.setAttempts(nil, true)
+		if err := task.EStatusSkip(reason); err == nil {
+			NewChainLogger(s.logger, task.Name).Printf("skipped: %v\n", reason)
+		}
+		return
+	}
+
+	_, err := task.Start(ctx)
+	if err != nil {
+		NewChainLogger(s.logger, "task", task.Name).Print(err.Error(), "\n")
+	}
+
+	if task.Probe.IsAlive() {
+		if ctx.Err() != nil || errors.Is(err, ErrStaleRun) {
+			// the run is interrupted or left behind, so the script doesn't finish the probe
+			task.Probe.Finish(context.WithoutCancel(ctx))
+			return
+		}
+		_ = s.probesStayedAlive.Enqueue(task.Probe)
+	}
+}
+
+// tasksSucceeded returns true if every task either succeeded or was skipped
+func tasksSucceeded(tasks []*Task) bool {
+	for _, t := range tasks {
+		r, status := t.Worker.Result()
+		if !r.Success && status != EStatusSkipped {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *Script) AddTask(t *Task) {
 	if s.EStatus != EStatusNew {
 		return
@@ -101,6 +239,7 @@ func (s *Script) Result() (r ScriptResult) {
 	rr, rs := s.Worker.Result()
 	r.Result = rr
 	r.Status = string(rs)
+	r.Timeouts = s.Timeouts()
 	r.Tasks = make([]TaskResult, len(s.Tasks))
 	for i, t := range s.Tasks {
 		r.Tasks[i] = t.Result()
@@ -110,11 +249,8 @@ func (s *Script) Result() (r ScriptResult) {
 
 func (s *Script) ResultFinished() (r ScriptResult) {
 	r.Result = s.Worker.ResultFinished()
-	if r.Result.Completed() {
-		r.Status = string(EStatusFinished)
-	} else {
-		r.Status = string(EStatusNew)
-	}
+	r.Status = string(s.Worker.StatusFinished())
+	r.Timeouts = s.Timeouts()
 	r.Tasks = make([]TaskResult, len(s.Tasks))
 	for i, t := range s.Tasks {
 		r.Tasks[i] = t.ResultFinished()
@@ -122,6 +258,13 @@ func (s *Script) ResultFinished() (r ScriptResult) {
 	return
 }
 
+// Timeouts returns the number of script runs interrupted by timeout
+func (s *Script) Timeouts() uint {
+	s.Lock()
+	defer s.Unlock()
+	return s.timeouts
+}
+
 func (s *Script) newCGroup(name string) (c *CGroup) {
 	c = &CGroup{
 		Worker: Worker{
@@ -138,36 +281,98 @@ func (s *Script) newCGroup(name string) (c *CGroup) {
 	return
 }
 
-func (s *Script) runCgroup(ctx context.Context, cgroup *CGroup) (succ bool) {
-	if err := cgroup.EStatusRun(); err != nil {
-		NewChainLogger(s.logger, "cgroup", cgroup.Name).Println(err.Error())
-		return
+// ResolveDependencies links tasks with the tasks they depend on and checks that the dependency graph has no cycles,
+// tasks without DependsOn wait for all tasks of the previous cgroup, so sequential cgroups keep their semantics
+func (s *Script) ResolveDependencies() error {
+	byName := make(map[string][]*Task, len(s.Tasks))
+	for _, t := range s.Tasks {
+		byName[t.Name] = append(byName[t.Name], t)
 	}
 
-	var wg sync.WaitGroup
-	for _, task := range cgroup.Tasks {
-		wg.Add(1)
-		go func(task *Task) {
-			defer func() {
-				wg.Done()
-			}()
-
-			_, err := task.Start(ctx)
+	for i, cgroup := range s.CGroups {
+		for _, t := range cgroup.Tasks {
+			t.after, t.dependencies = nil, nil
+			if len(t.DependsOn) > 0 {
+				continue
+			}
+			if i > 0 {
+				t.after = s.CGroups[i-1].Tasks
+			}
+		}
+	}
+	lookup := func(t *Task, name string) (*Task, error) {
+		switch deps := byName[name]; len(deps) {
+		case 0:
+			return nil, fmt.Errorf("[%v] %w: unknown task %v", t.Name, ErrDependency, name)
+		case 1:
+			return deps[0], nil
+		default:
+			return nil, fmt.Errorf("[%v] %w: task name %v isn't unique", t.Name, ErrDependency, name)
+		}
+	}
+	for _, t := range s.Tasks {
+		for _, name := range t.DependsOn {
+			d, err := lookup(t, name)
 			if err != nil {
-				NewChainLogger(s.logger, "task", task.Name).Print(err.Error(), "\n")
+				return err
 			}
+			t.dependencies = append(t.dependencies, d)
+		}
+		// tasks referenced by conditions and configuration templates have to be finished
+		// before the conditions are evaluated and the configuration is rendered
+		var referenced []string
+		for _, c := range []*Condition{t.RunIf, t.SkipIf} {
+			if c != nil {
+				referenced = append(referenced, c.Tasks()...)
+			}
+		}
+		if renderer, ok := t.Probe.(ConfigRenderer); ok {
+			referenced = append(referenced, renderer.Tasks()...)
+		}
+		for _, name := range referenced {
+			d, err := lookup(t, name)
+			if err != nil {
+				return err
+			}
+			t.after = append(t.after[:len(t.after):len(t.after)], d)
+		}
+	}
 
-			if task.Probe.IsAlive() {
-				_ = s.probesStayedAlive.Enqueue(task.Probe)
+	// depth-first search for a back edge
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*Task]int, len(s.Tasks))
+	var path []string
+	var visit func(t *Task) error
+	visit = func(t *Task) error {
+		switch state[t] {
+		case visiting:
+			return fmt.Errorf("%w: cycle %v", ErrDependency, strings.Join(append(path, t.Name), " -> "))
+		case visited:
+			return nil
+		}
+		state[t] = visiting
+		path = append(path, t.Name)
+		for _, waitFor := range [][]*Task{t.after, t.dependencies} {
+			for _, d := range waitFor {
+				if err := visit(d); err != nil {
+					return err
+				}
 			}
-		}(task)
+		}
+		path = path[:len(path)-1]
+		state[t] = visited
+		return nil
 	}
-	wg.Wait()
-	succ = true
 	for _, t := range s.Tasks {
-		taskResult, _ := t.Worker.Result()
-		succ = succ && taskResult.Success
+		if err := visit(t); err != nil {
+			return err
+		}
 	}
-	_ = cgroup.EStatusFinish(succ)
-	return
+
+	s.resolved = true
+	return nil
 }
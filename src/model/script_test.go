@@ -0,0 +1,348 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testProbe struct {
+	ProbeHandler
+}
+
+func newTestProbe(runner ProbeRunner) *testProbe {
+	p := testProbe{}
+	p.Name = "test"
+	p.ProbeOptions = DefaultProbeOptions
+	p.SetRunner(runner)
+	return &p
+}
+
+func testRunnerSucceeded(context.Context) (bool, any) {
+	return true, nil
+}
+
+func testRunnerWaitingContext(ctx context.Context) (bool, any) {
+	<-ctx.Done()
+	return false, nil
+}
+
+func testRunnerHung(context.Context) (bool, any) {
+	time.Sleep(time.Second * 2)
+	return true, nil
+}
+
+func Test_ScriptTimeout(t *testing.T) {
+	TimeoutGracePeriod = time.Millisecond * 100
+	var finished atomic.Bool
+
+	background := newTestProbe(func(ctx context.Context) (bool, any) {
+		return true, nil
+	})
+	background.CanStayBackground = true
+	background.StayBackground = true
+	background.SetFinisher(func(context.Context) {
+		finished.Store(true)
+	})
+
+	s := &Script{Timeout: time.Millisecond * 200}
+	s.AddTask(NewTask("fast", "1", TaskMetric{}, newTestProbe(testRunnerSucceeded)))
+	s.AddTask(NewTask("background", "1", TaskMetric{}, background))
+	s.AddTask(NewTask("waiting", "2", TaskMetric{}, newTestProbe(testRunnerWaitingContext)))
+	s.AddTask(NewTask("hung", "2", TaskMetric{}, newTestProbe(testRunnerHung)))
+	s.AddTask(NewTask("not-started", "3", TaskMetric{}, newTestProbe(testRunnerSucceeded)))
+
+	started := time.Now()
+	s.Run(context.Background())
+	if d := time.Since(started); d > time.Second {
+		t.Fatalf("script should be interrupted by timeout, but it took %v", d)
+	}
+
+	r := s.ResultFinished()
+	if r.Success {
+		t.Fatal("script result should be failed")
+	}
+	if r.Status != string(EStatusTimeout) {
+		t.Fatalf("script status should be %v, got %v", EStatusTimeout, r.Status)
+	}
+	if r.Timeouts != 1 {
+		t.Fatalf("script timeouts should be 1, got %v", r.Timeouts)
+	}
+
+	expected := map[string]EStatus{
+		"fast":        EStatusFinished,
+		"background":  EStatusFinished,
+		"waiting":     EStatusTimeout,
+		"hung":        EStatusTimeout,
+		"not-started": EStatusTimeout,
+	}
+	for _, tr := range r.Tasks {
+		if tr.Status != string(expected[tr.Name]) {
+			t.Errorf("task %v status should be %v, got %v", tr.Name, expected[tr.Name], tr.Status)
+		}
+		if tr.Status == string(EStatusTimeout) && tr.Success {
+			t.Errorf("task %v result should be failed", tr.Name)
+		}
+	}
+
+	if !finished.Load() {
+		t.Fatal("background probe should be finished")
+	}
+}
+
+// testStartProbe is a prober which can be started concurrently, it stays alive if start sets alive
+type testStartProbe struct {
+	start    func(ctx context.Context) bool
+	alive    atomic.Bool
+	finishes atomic.Int32
+}
+
+func (p *testStartProbe) Start(ctx context.Context) bool { return p.start(ctx) }
+func (p *testStartProbe) Finish(context.Context) {
+	p.finishes.Add(1)
+	p.alive.Store(false)
+}
+func (p *testStartProbe) Error() error                    { return nil }
+func (p *testStartProbe) IsAlive() bool                   { return p.alive.Load() }
+func (p *testStartProbe) Result() (r ProbeResult)         { return }
+func (p *testStartProbe) ResultFinished() (r ProbeResult) { return }
+
+func Test_ScriptTimeoutStaleTask(t *testing.T) {
+	TimeoutGracePeriod = time.Millisecond * 50
+	var calls atomic.Int32
+	probe := &testStartProbe{start: func(ctx context.Context) bool {
+		if calls.Add(1) == 1 {
+			// the first run is hung past the start of the next one
+			time.Sleep(time.Millisecond * 300)
+			return true
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Millisecond * 600):
+		}
+		return false
+	}}
+	s := &Script{Timeout: time.Millisecond * 100}
+	s.AddTask(NewTask("hung", "1", TaskMetric{}, probe))
+
+	s.Run(context.Background())
+	if r := s.ResultFinished(); r.Status != string(EStatusTimeout) {
+		t.Fatalf("the first run should be interrupted, got %v", r.Status)
+	}
+
+	// the first run of the task is finished during the second one
+	s.Timeout = time.Second * 2
+	s.Run(context.Background())
+	tr := s.ResultFinished().Tasks[0]
+	if tr.Status != string(EStatusFinished) || tr.Success || tr.RunCounter != 2 {
+		t.Fatalf("the second run should be finished with its own failed result, got %v %+v", tr.Status, tr.Result)
+	}
+	if len(tr.Attempts) != 1 || tr.Attempts[0].Success {
+		t.Fatalf("the second run should have its own failed attempt, got %+v", tr.Attempts)
+	}
+}
+
+func Test_ScriptTimeoutStaleBackgroundProbe(t *testing.T) {
+	TimeoutGracePeriod = time.Millisecond * 50
+	var probe *testStartProbe
+	probe = &testStartProbe{start: func(context.Context) bool {
+		// the probe ignores the cancellation and stays in background
+		time.Sleep(time.Millisecond * 300)
+		probe.alive.Store(true)
+		return true
+	}}
+	s := &Script{Timeout: time.Millisecond * 100}
+	s.AddTask(NewTask("background", "1", TaskMetric{}, probe))
+	s.Run(context.Background())
+
+	for i := 0; i < 100 && probe.finishes.Load() == 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if probe.finishes.Load() != 1 || probe.IsAlive() {
+		t.Fatal("the background probe of the task left behind should be finished")
+	}
+	if s.probesStayedAlive.GetLen() != 0 {
+		t.Fatal("the background probe of the task left behind shouldn't be kept for the script")
+	}
+}
+
+func Test_ScriptWithoutTimeout(t *testing.T) {
+	s := &Script{}
+	s.AddTask(NewTask("first", "1", TaskMetric{}, newTestProbe(testRunnerSucceeded)))
+	s.AddTask(NewTask("second", "2", TaskMetric{}, newTestProbe(testRunnerSucceeded)))
+
+	for i := 0; i < 2; i++ {
+		s.Run(context.Background())
+		r := s.ResultFinished()
+		if !r.Success || r.Status != string(EStatusFinished) || r.Timeouts != 0 {
+			t.Fatalf("script should be finished successfully, got %+v", r)
+		}
+		for _, tr := range r.Tasks {
+			if tr.Status != string(EStatusFinished) || tr.RunCounter != uint(i+1) {
+				t.Fatalf("task %v should be finished %v times, got %+v", tr.Name, i+1, tr)
+			}
+		}
+	}
+}
+
+func testRunnerFailed(context.Context) (bool, any) {
+	return false, nil
+}
+
+func Test_ScriptDependencies(t *testing.T) {
+	slow := func(ctx context.Context) (bool, any) {
+		time.Sleep(time.Millisecond * 300)
+		return true, nil
+	}
+	var quickStarted, slowFinished atomic.Int64
+	quick := newTestProbe(func(context.Context) (bool, any) {
+		quickStarted.Store(time.Now().UnixNano())
+		return true, nil
+	})
+	slowTask := newTestProbe(func(ctx context.Context) (bool, any) {
+		defer func() { slowFinished.Store(time.Now().UnixNano()) }()
+		return slow(ctx)
+	})
+
+	s := &Script{}
+	add := func(name, cgroup string, probe Prober, dependsOn ...string) {
+		task := NewTask(name, cgroup, TaskMetric{}, probe)
+		task.DependsOn = dependsOn
+		s.AddTask(task)
+	}
+	add("slow", "", slowTask)
+	add("login", "", newTestProbe(testRunnerSucceeded))
+	add("broken", "", newTestProbe(testRunnerFailed))
+	add("after-login", "", quick, "login")
+	add("after-broken", "", newTestProbe(testRunnerSucceeded), "broken")
+	add("after-skipped", "", newTestProbe(testRunnerSucceeded), "after-broken", "login")
+	if err := s.ResolveDependencies(); err != nil {
+		t.Fatalf("dependencies should be resolved: %v", err)
+	}
+
+	s.Run(context.Background())
+	if quickStarted.Load() > slowFinished.Load() {
+		t.Fatal("independent task should be started without waiting for the slow task")
+	}
+
+	r := s.ResultFinished()
+	if r.Success {
+		t.Fatal("script result should be failed")
+	}
+	expected := map[string]struct {
+		status EStatus
+		reason string
+	}{
+		"slow":          {EStatusFinished, ""},
+		"login":         {EStatusFinished, ""},
+		"broken":        {EStatusFinished, ""},
+		"after-login":   {EStatusFinished, ""},
+		"after-broken":  {EStatusSkipped, "dependency broken has failed"},
+		"after-skipped": {EStatusSkipped, "dependency after-broken is skipped"},
+	}
+	for _, tr := range r.Tasks {
+		e := expected[tr.Name]
+		if tr.Status != string(e.status) || tr.Reason != e.reason {
+			t.Errorf("task %v should be %v (%v), got %v (%v)", tr.Name, e.status, e.reason, tr.Status, tr.Reason)
+		}
+	}
+}
+
+func Test_ScriptCGroupsOrder(t *testing.T) {
+	var firstFinished atomic.Bool
+	first := newTestProbe(func(context.Context) (bool, any) {
+		time.Sleep(time.Millisecond * 100)
+		firstFinished.Store(true)
+		return false, nil
+	})
+	var startedAfterFirst atomic.Bool
+	second := newTestProbe(func(context.Context) (bool, any) {
+		startedAfterFirst.Store(firstFinished.Load())
+		return true, nil
+	})
+
+	s := &Script{}
+	s.AddTask(NewTask("first", "1", TaskMetric{}, first))
+	s.AddTask(NewTask("second", "2", TaskMetric{}, second))
+	s.Run(context.Background())
+
+	if !startedAfterFirst.Load() {
+		t.Fatal("the next cgroup should be started after the previous one is finished")
+	}
+	for _, tr := range s.ResultFinished().Tasks {
+		if tr.Status != string(EStatusFinished) {
+			t.Fatalf("task %v should be finished regardless of the previous cgroup result, got %v", tr.Name, tr.Status)
+		}
+	}
+}
+
+func Test_ResolveDependencies(t *testing.T) {
+	type testCase struct {
+		name  string
+		tasks [][]string // task name, cgroup, dependencies...
+	}
+	cases := []testCase{
+		{"unknown dependency", [][]string{{"a", "", "b"}}},
+		{"self dependency", [][]string{{"a", "", "a"}}},
+		{"cycle", [][]string{{"a", "", "c"}, {"b", "", "a"}, {"c", "", "b"}}},
+		{"cycle through cgroup order", [][]string{{"a", "1", "b"}, {"b", "2"}}},
+		{"ambiguous dependency", [][]string{{"a", "1"}, {"a", "2"}, {"b", "3", "a"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &Script{}
+			for _, task := range c.tasks {
+				tt := NewTask(task[0], task[1], TaskMetric{}, newTestProbe(testRunnerSucceeded))
+				tt.DependsOn = task[2:]
+				s.AddTask(tt)
+			}
+			if err := s.ResolveDependencies(); !errors.Is(err, ErrDependency) {
+				t.Fatalf("dependency error should be returned, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_ScriptConditions(t *testing.T) {
+	s := &Script{}
+	add := func(name, cgroup string, probe Prober, runIf, skipIf string) {
+		task := NewTask(name, cgroup, TaskMetric{}, probe)
+		if runIf != "" {
+			task.RunIf, _ = NewCondition(runIf)
+		}
+		if skipIf != "" {
+			task.SkipIf, _ = NewCondition(skipIf)
+		}
+		s.AddTask(task)
+	}
+	add("ping", "1", newTestProbe(testRunnerFailed), "", "")
+	add("vpn", "1", newTestProbe(testRunnerSucceeded), "", "")
+	add("traceroute", "", newTestProbe(testRunnerSucceeded), "not .tasks.ping.success", "")
+	add("backup-vpn", "", newTestProbe(testRunnerSucceeded), "", ".tasks.vpn.success")
+	add("after-skipped", "", newTestProbe(testRunnerSucceeded), `eq (index .tasks "backup-vpn" "status") "skipped"`, "")
+	add("wrong-condition", "", newTestProbe(testRunnerSucceeded), `gt .tasks.ping.status 1`, "")
+	if err := s.ResolveDependencies(); err != nil {
+		t.Fatalf("dependencies should be resolved: %v", err)
+	}
+	s.Run(context.Background())
+
+	expected := map[string]EStatus{
+		"ping":          EStatusFinished,
+		"vpn":           EStatusFinished,
+		"traceroute":    EStatusFinished,
+		"backup-vpn":    EStatusSkipped,
+		"after-skipped": EStatusFinished,
+		// the condition which can't be evaluated skips the task
+		"wrong-condition": EStatusSkipped,
+	}
+	for _, tr := range s.ResultFinished().Tasks {
+		if tr.Status != string(expected[tr.Name]) {
+			t.Errorf("task %v status should be %v, got %v (%v)", tr.Name, expected[tr.Name], tr.Status, tr.Reason)
+		}
+	}
+	if r := s.ResultFinished().Tasks[3].Reason; r != `skipIf condition ".tasks.vpn.success" is true` {
+		t.Errorf("skipped task reason is wrong: %v", r)
+	}
+}
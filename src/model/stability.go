@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+)
+
+const (
+	DefaultStabilityWindow = 10
+	DefaultFlapThreshold   = 4
+)
+
+// Stability is the configuration of the stable task state, the stable state is changed after
+// FailThreshold failed or RecoverThreshold successful results in a row, zero values are replaced with defaults
+type Stability struct {
+	FailThreshold    int `json:"failThreshold,omitempty"`    // 1 by default
+	RecoverThreshold int `json:"recoverThreshold,omitempty"` // 1 by default
+	Window           int `json:"window,omitempty"`           // the number of the latest results checked for flapping
+	FlapThreshold    int `json:"flapThreshold,omitempty"`    // the number of result changes within the window to be flapping
+}
+
+// TaskStability is the stable state of the task computed from its latest results
+type TaskStability struct {
+	StableSuccess bool `json:"stableSuccess"`
+	Flapping      bool `json:"flapping"`
+}
+
+// WithDefaults returns the configuration with the default values set, nil is the default configuration
+func (s *Stability) WithDefaults() (c Stability) {
+	if s != nil {
+		c = *s
+	}
+	if c.FailThreshold == 0 {
+		c.FailThreshold = 1
+	}
+	if c.RecoverThreshold == 0 {
+		c.RecoverThreshold = 1
+	}
+	if c.Window == 0 {
+		c.Window = DefaultStabilityWindow
+	}
+	if c.FlapThreshold == 0 {
+		c.FlapThreshold = DefaultFlapThreshold
+	}
+	return
+}
+
+// Validate checks the configuration with the default values set
+func (s *Stability) Validate() error {
+	c := s.WithDefaults()
+	if c.FailThreshold < 0 || c.RecoverThreshold < 0 || c.Window < 0 || c.FlapThreshold < 0 {
+		return errors.New("stability values can't be negative")
+	}
+	if c.FlapThreshold >= c.Window {
+		return errors.New("stability flapThreshold should be less than window")
+	}
+	return nil
+}
+
+// SameAs returns true if the configurations are equal
+func (s *Stability) SameAs(o *Stability) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	return *s == *o
+}
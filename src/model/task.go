@@ -1,49 +1,209 @@
 package model
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
 type Task struct {
-	Name   string
-	CGroup string     `json:"-"`
-	Metric TaskMetric `json:"-"`
-	Probe  Prober
+	Name      string
+	CGroup    string     `json:"-"`
+	DependsOn []string   `json:"-"` // tasks that must succeed before the task is started
+	RunIf     *Condition `json:"-"` // the task is started only if the condition is true
+	SkipIf    *Condition `json:"-"` // the task is skipped if the condition is true
+	Metric    TaskMetric `json:"-"`
+	SLO       float64    `json:"-"` // the target success ratio, e.g. 0.999, 0 isn't defined
+	Probe     Prober
 	Worker
+	dependencies []*Task // resolved DependsOn
+	after        []*Task // tasks to wait for regardless of their result, the previous cgroup and conditions tasks
+	attempts     []TaskAttempt
+	prevAttempts []TaskAttempt // attempts of the last finished run
+	attemptsLock sync.Mutex
 }
 
 type TaskResult struct {
-	Name   string      `json:"name"`
-	Status string      `json:"status"`
-	Probe  ProbeResult `json:"probe"`
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Probe    ProbeResult   `json:"probe"`
+	Attempts []TaskAttempt `json:"attempts"`
 	Result
+	*TaskStability // set by the scheduler only
+	*StateTimes    // set by the scheduler only
 }
 
+// TaskAttempt is the outcome of a single probe run
+type TaskAttempt struct {
+	Success   bool        `json:"success"`
+	RuntimeMs int         `json:"runtime"`
+	Error     string      `json:"error,omitempty"`
+	Reason    ErrorReason `json:"reason,omitempty"` // the failure reason of the probe or its first failed target
+}
+
+// Start runs the probe, a failed probe is run again until success or the probe retries are exhausted,
+// a retry isn't started if the context deadline is going to expire before the retry delay is passed,
+// a run left behind after the script timeout doesn't change the results of the next run
 func (t *Task) Start(ctx context.Context) (succ bool, err error) {
-	if err = t.EStatusRun(); err != nil {
+	run, err := t.EStatusStart()
+	if err != nil {
 		return
 	}
-	succ = t.Probe.Start(ContextWithLogger(ctx, NewChainLogger(GetLogger(ctx), t.Name)))
-	err = t.EStatusFinish(succ)
+	t.setRunAttempts(run, nil, false)
+	if renderer, ok := t.Probe.(ConfigRenderer); ok {
+		if err = renderer.Render(map[string]any{"tasks": t.afterVars()}); err != nil {
+			GetLogger(ctx).Printf("[%v] %v\n", t.Name, err)
+			t.setRunAttempts(run, []TaskAttempt{{Error: err.Error()}}, true)
+			_ = t.EStatusFinishRun(run, false)
+			return false, err
+		}
+	}
+	options := t.Probe.Result().Options
+	var attempts []TaskAttempt
+	for retry := uint(0); ; retry++ {
+		if retry > 0 {
+			delay := options.RetryDelayFor(retry)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+				break
+			}
+			GetLogger(ctx).Printf("[%v] retry %v of %v in %v\n", t.Name, retry, options.Retries, delay)
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		succ = t.Probe.Start(ContextWithLogger(ctx, NewChainLogger(GetLogger(ctx), t.Name)))
+		r := t.Probe.Result()
+		attempt := TaskAttempt{Success: succ, RuntimeMs: r.RuntimeMs}
+		if !succ {
+			attempt.Error, attempt.Reason = attemptError(r, t.Probe.Error())
+		}
+		attempts = append(attempts, attempt)
+		t.setRunAttempts(run, attempts, false)
+		if succ || retry >= options.Retries || ctx.Err() != nil {
+			break
+		}
+	}
+	t.setRunAttempts(run, attempts, true)
+
+	// the probe has been interrupted by the script timeout
+	if !succ && ctx.Err() != nil {
+		t.EStatusTimeoutRun(run)
+		return succ, ErrTimeout
+	}
+	err = t.EStatusFinishRun(run, succ)
 	return
 }
 
+// attemptError joins the probe error and the errors of the probe targets,
+// the reason is the probe error one or the reason of the first failed target in the order of the targets
+func attemptError(r ProbeResult, err error) (msg string, reason ErrorReason) {
+	var msgs []string
+	if err != nil {
+		msgs = append(msgs, err.Error())
+		reason = ErrorReasonOf(err)
+	}
+	targets := make([]string, 0, len(r.Errors))
+	for target := range r.Errors {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		e := r.Errors[target]
+		msgs = append(msgs, target+": "+e.Message)
+		if reason == "" {
+			reason = e.Reason
+		}
+	}
+	return strings.Join(msgs, "; "), reason
+}
+
+// setAttempts sets the attempts of the current run, finished means the run is over
+func (t *Task) setAttempts(attempts []TaskAttempt, finished bool) {
+	t.attemptsLock.Lock()
+	defer t.attemptsLock.Unlock()
+	t.attempts = append([]TaskAttempt(nil), attempts...)
+	if finished {
+		t.prevAttempts = t.attempts
+	}
+}
+
+// setRunAttempts sets the attempts if the run is the current one
+func (t *Task) setRunAttempts(run uint, attempts []TaskAttempt, finished bool) {
+	t.attemptsLock.Lock()
+	defer t.attemptsLock.Unlock()
+	if !t.IsRun(run) {
+		return
+	}
+	t.attempts = append([]TaskAttempt(nil), attempts...)
+	if finished {
+		t.prevAttempts = t.attempts
+	}
+}
+
+// conditionsSkipReason evaluates runIf and skipIf conditions with the current results of the referenced tasks,
+// returns the reason to skip the task or empty string, the task is skipped if a condition can't be evaluated
+func (t *Task) conditionsSkipReason() string {
+	if t.RunIf == nil && t.SkipIf == nil {
+		return ""
+	}
+	vars := t.afterVars()
+	if t.SkipIf != nil {
+		skip, err := t.SkipIf.Eval(vars)
+		if err != nil {
+			return fmt.Sprintf("skipIf condition %q can't be evaluated: %v", t.SkipIf.Source, err)
+		}
+		if skip {
+			return fmt.Sprintf("skipIf condition %q is true", t.SkipIf.Source)
+		}
+	}
+	if t.RunIf != nil {
+		run, err := t.RunIf.Eval(vars)
+		if err != nil {
+			return fmt.Sprintf("runIf condition %q can't be evaluated: %v", t.RunIf.Source, err)
+		}
+		if !run {
+			return fmt.Sprintf("runIf condition %q is false", t.RunIf.Source)
+		}
+	}
+	return ""
+}
+
+// afterVars returns the current results of the tasks the task waits for
+func (t *Task) afterVars() map[string]any {
+	vars := make(map[string]any, len(t.after))
+	for _, d := range t.after {
+		vars[d.Name] = ConditionTaskVars(d.Result())
+	}
+	return vars
+}
+
 func (t *Task) Result() (tr TaskResult) {
 	tr.Name = t.Name
 	trr, trs := t.Worker.Result()
 	tr.Result = trr
 	tr.Status = string(trs)
 	tr.Probe = t.Probe.Result()
+	t.attemptsLock.Lock()
+	tr.Attempts = t.attempts
+	t.attemptsLock.Unlock()
 	return
 }
 
 func (t *Task) ResultFinished() (tr TaskResult) {
 	tr.Name = t.Name
 	tr.Result = t.Worker.ResultFinished()
-	if tr.Result.Completed() {
-		tr.Status = string(EStatusFinished)
-	} else {
-		tr.Status = string(EStatusNew)
-	}
+	tr.Status = string(t.Worker.StatusFinished())
 	tr.Probe = t.Probe.ResultFinished()
+	t.attemptsLock.Lock()
+	tr.Attempts = t.prevAttempts
+	t.attemptsLock.Unlock()
 	return
 }
 
@@ -2,11 +2,17 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 )
 
 type TaskMetric struct {
-	Labels   MetricLabels
-	ValueMap MetricLabelsValueMap
+	Labels    MetricLabels
+	ValueMap  MetricLabelsValueMap
+	Type      string    `json:"type"`      // gauge (default), histogram or summary
+	Buckets   []float64 `json:"buckets"`   // histogram buckets, DefaultMetricBuckets by default
+	Quantiles []float64 `json:"quantiles"` // summary quantiles, DefaultMetricQuantiles by default
+	Fields    []string  `json:"fields"`    // observed fields of the probe data structure, all numeric fields by default
 }
 
 type MetricLabels struct {
@@ -36,3 +42,40 @@ func (s *MetricLabels) Data() map[string]string {
 func (s *MetricLabels) IsEmpty() bool {
 	return !(len(s.data) > 0)
 }
+
+const (
+	MetricTypeGauge     = "gauge"
+	MetricTypeHistogram = "histogram"
+	MetricTypeSummary   = "summary"
+)
+
+// DefaultMetricBuckets are histogram buckets for values in milliseconds
+var DefaultMetricBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// DefaultMetricQuantiles are summary quantiles
+var DefaultMetricQuantiles = []float64{0.5, 0.9, 0.99}
+
+// IsDistribution returns true if the task runtime and numeric probe data are observed by a histogram or a summary
+func (m *TaskMetric) IsDistribution() bool {
+	return m.Type == MetricTypeHistogram || m.Type == MetricTypeSummary
+}
+
+// Validate checks the metric type, buckets and quantiles
+func (m *TaskMetric) Validate() error {
+	switch m.Type {
+	case "", MetricTypeGauge, MetricTypeHistogram, MetricTypeSummary:
+	default:
+		return fmt.Errorf("unknown metric type %v", m.Type)
+	}
+	for i, b := range m.Buckets {
+		if i > 0 && b <= m.Buckets[i-1] {
+			return errors.New("metric buckets should be in increasing order")
+		}
+	}
+	for _, q := range m.Quantiles {
+		if q <= 0 || q >= 1 {
+			return fmt.Errorf("metric quantile %v should be between 0 and 1", q)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_TaskRetries(t *testing.T) {
+	type testCase struct {
+		name             string
+		failures         int
+		options          ProbeOptions
+		deadline         time.Duration
+		expectedResult   bool
+		expectedAttempts int
+	}
+	cases := []testCase{
+		{"no retries", 1, ProbeOptions{}, 0, false, 1},
+		{"succeeds on retry", 2, ProbeOptions{Retries: 3, RetryDelay: time.Millisecond * 10}, 0, true, 3},
+		{"retries are exhausted", 5, ProbeOptions{Retries: 2, RetryDelay: time.Millisecond * 10, RetryBackoff: 2}, 0, false, 3},
+		{"retry delay exceeds deadline", 5, ProbeOptions{Retries: 3, RetryDelay: time.Millisecond * 500}, time.Millisecond * 200, false, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runs := 0
+			p := newTestProbe(func(context.Context) (bool, any) {
+				runs++
+				return runs > c.failures, nil
+			})
+			p.ProbeOptions = c.options
+			task := NewTask("retries", "", TaskMetric{}, p)
+
+			ctx := context.Background()
+			if c.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.deadline)
+				defer cancel()
+			}
+			started := time.Now()
+			succ, _ := task.Start(ctx)
+			if succ != c.expectedResult {
+				t.Fatalf("task should return %v", c.expectedResult)
+			}
+			if c.deadline > 0 && time.Since(started) >= c.deadline {
+				t.Fatal("task shouldn't wait for a retry after the deadline")
+			}
+			r := task.ResultFinished()
+			if len(r.Attempts) != c.expectedAttempts {
+				t.Fatalf("task should have %v attempts, got %v", c.expectedAttempts, len(r.Attempts))
+			}
+			if last := r.Attempts[len(r.Attempts)-1]; last.Success != c.expectedResult {
+				t.Fatalf("the last attempt result should be %v", c.expectedResult)
+			}
+		})
+	}
+}
+
+func Test_TaskAttemptTargetErrors(t *testing.T) {
+	var p *testProbe
+	p = newTestProbe(func(context.Context) (bool, any) {
+		p.SetTargetError("b", NewReasonError(ReasonWrongStatus, errors.New("status 500")))
+		p.SetTargetError("a", context.DeadlineExceeded)
+		return false, nil
+	})
+	task := NewTask("targets", "", TaskMetric{}, p)
+	if succ, _ := task.Start(context.Background()); succ {
+		t.Fatal("task should fail")
+	}
+	attempts := task.ResultFinished().Attempts
+	if len(attempts) != 1 {
+		t.Fatalf("task should have 1 attempt, got %v", len(attempts))
+	}
+	if a := attempts[0]; a.Error != "a: context deadline exceeded; b: status 500" || a.Reason != ReasonTimeout {
+		t.Fatalf("attempt should have the target errors, got %+v", a)
+	}
+}
+
+func Test_RetryDelayFor(t *testing.T) {
+	o := ProbeOptions{RetryDelay: time.Millisecond * 100, RetryBackoff: 2}
+	for retry, expected := range []time.Duration{100, 200, 400} {
+		if d := o.RetryDelayFor(uint(retry + 1)); d != expected*time.Millisecond {
+			t.Fatalf("retry %v delay should be %v, got %v", retry+1, expected*time.Millisecond, d)
+		}
+	}
+}
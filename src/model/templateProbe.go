@@ -0,0 +1,280 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+var ErrTemplate = errors.New("can't render probe configuration")
+
+// ProbeFactory creates a probe from the json configuration
+type ProbeFactory func(configuration []byte) (Prober, error)
+
+// ConfigRenderer is implemented by probes with the configuration rendered at run time from the results of other tasks
+type ConfigRenderer interface {
+	// Render creates the probe with the configuration rendered with the task results, vars are {"tasks": {name: result}}
+	Render(vars map[string]any) error
+	// Tasks returns the names of the tasks referenced by the configuration templates
+	Tasks() []string
+}
+
+// TemplateProbe is a probe with text/template expressions in the configuration string values, e.g.
+// url: "https://example.com/profile?token={{ .tasks.login.data.capture }}",
+// the probe is created by the factory with the rendered configuration every run
+type TemplateProbe struct {
+	Name      string
+	Options   ProbeOptions
+	Config    any // configuration with templates
+	compiled  any // Config with *template.Template instead of templated strings
+	tasks     []string
+	factory   ProbeFactory
+	probe     Prober // the probe created with the last rendered configuration
+	renderErr error
+	failed    Result // result of the run failed on rendering
+	sync.Mutex
+}
+
+// NewTemplateProbe parses the templates of the json configuration
+func NewTemplateProbe(name string, options ProbeOptions, configuration []byte, factory ProbeFactory) (p *TemplateProbe, err error) {
+	p = &TemplateProbe{Name: name, Options: options, factory: factory}
+	if err = json.Unmarshal(configuration, &p.Config); err != nil {
+		return nil, err
+	}
+	if p.compiled, err = p.compile(p.Config); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// IsTemplate returns true if the json configuration contains template expressions
+func IsTemplate(configuration []byte) bool {
+	return bytes.Contains(configuration, []byte("{{"))
+}
+
+func (p *TemplateProbe) compile(v any) (any, error) {
+	switch c := v.(type) {
+	case string:
+		if !strings.Contains(c, "{{") {
+			return c, nil
+		}
+		t, err := template.New(p.Name).Option("missingkey=error").Parse(c)
+		if err != nil {
+			return nil, err
+		}
+		p.tasks = append(p.tasks, templateTasks(t.Tree.Root)...)
+		return t, nil
+	case map[string]any:
+		r := make(map[string]any, len(c))
+		for k, item := range c {
+			compiled, err := p.compile(item)
+			if err != nil {
+				return nil, err
+			}
+			r[k] = compiled
+		}
+		return r, nil
+	case []any:
+		r := make([]any, len(c))
+		for i, item := range c {
+			compiled, err := p.compile(item)
+			if err != nil {
+				return nil, err
+			}
+			r[i] = compiled
+		}
+		return r, nil
+	}
+	return v, nil
+}
+
+func (p *TemplateProbe) render(v any, vars map[string]any) (any, error) {
+	switch c := v.(type) {
+	case *template.Template:
+		var b strings.Builder
+		if err := c.Execute(&b, vars); err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	case map[string]any:
+		r := make(map[string]any, len(c))
+		for k, item := range c {
+			rendered, err := p.render(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			r[k] = rendered
+		}
+		return r, nil
+	case []any:
+		r := make([]any, len(c))
+		for i, item := range c {
+			rendered, err := p.render(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			r[i] = rendered
+		}
+		return r, nil
+	}
+	return v, nil
+}
+
+// Render renders the configuration and creates the probe, the probe fails the next run on error
+func (p *TemplateProbe) Render(vars map[string]any) (err error) {
+	defer func() {
+		p.Lock()
+		defer p.Unlock()
+		p.renderErr = nil
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrTemplate, err)
+			p.renderErr = err
+			p.failed.PrepareToStart()
+			p.failed.End(false)
+		}
+	}()
+
+	p.Lock()
+	alive := p.probe != nil && p.probe.IsAlive()
+	p.Unlock()
+	if alive {
+		return errors.New("the probe created with the previous configuration is still running")
+	}
+
+	rendered, err := p.render(p.compiled, vars)
+	if err != nil {
+		return
+	}
+	configuration, err := json.Marshal(rendered)
+	if err != nil {
+		return
+	}
+	probe, err := p.factory(configuration)
+	if err != nil {
+		return
+	}
+	p.Lock()
+	p.probe = probe
+	p.Unlock()
+	return
+}
+
+// Tasks returns the names of the tasks referenced by the configuration templates
+func (p *TemplateProbe) Tasks() []string {
+	return p.tasks
+}
+
+func (p *TemplateProbe) current() (Prober, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.probe, p.renderErr
+}
+
+func (p *TemplateProbe) Start(ctx context.Context) (succ bool) {
+	probe, err := p.current()
+	if err != nil || probe == nil {
+		return false
+	}
+	return probe.Start(ctx)
+}
+
+func (p *TemplateProbe) Finish(ctx context.Context) {
+	if probe, _ := p.current(); probe != nil {
+		probe.Finish(ctx)
+	}
+}
+
+func (p *TemplateProbe) Error() error {
+	probe, err := p.current()
+	if err != nil || probe == nil {
+		return err
+	}
+	return probe.Error()
+}
+
+func (p *TemplateProbe) IsAlive() bool {
+	probe, _ := p.current()
+	return probe != nil && probe.IsAlive()
+}
+
+func (p *TemplateProbe) Result() (r ProbeResult) {
+	return p.result(Prober.Result)
+}
+
+func (p *TemplateProbe) ResultFinished() (r ProbeResult) {
+	return p.result(Prober.ResultFinished)
+}
+
+// result returns the result of the probe created with the rendered configuration,
+// the configuration is omitted until it's rendered, as the templates aren't redacted by the probe
+func (p *TemplateProbe) result(probeResult func(Prober) ProbeResult) (r ProbeResult) {
+	p.Lock()
+	probe, err, failed := p.probe, p.renderErr, p.failed
+	p.Unlock()
+	if err == nil && probe != nil {
+		return probeResult(probe)
+	}
+	r.Name = p.Name
+	r.Options = p.Options
+	if err != nil {
+		r.Result = failed
+	}
+	return
+}
+
+// templateTasks returns the task names referenced in the template as .tasks.<name> or index .tasks "<name>"
+func templateTasks(node parse.Node) (tasks []string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, item := range n.Nodes {
+			tasks = append(tasks, templateTasks(item)...)
+		}
+	case *parse.ActionNode:
+		tasks = templateTasks(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			tasks = append(tasks, templateTasks(cmd)...)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) >= 3 {
+			ident, isIdent := n.Args[0].(*parse.IdentifierNode)
+			field, isField := n.Args[1].(*parse.FieldNode)
+			name, isString := n.Args[2].(*parse.StringNode)
+			if isIdent && ident.Ident == "index" && isField && len(field.Ident) == 1 && field.Ident[0] == "tasks" && isString {
+				tasks = append(tasks, name.Text)
+			}
+		}
+		for _, arg := range n.Args {
+			tasks = append(tasks, templateTasks(arg)...)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) >= 2 && n.Ident[0] == "tasks" {
+			tasks = append(tasks, n.Ident[1])
+		}
+	case *parse.IfNode:
+		tasks = templateBranchTasks(&n.BranchNode)
+	case *parse.RangeNode:
+		tasks = templateBranchTasks(&n.BranchNode)
+	case *parse.WithNode:
+		tasks = templateBranchTasks(&n.BranchNode)
+	}
+	return
+}
+
+func templateBranchTasks(n *parse.BranchNode) (tasks []string) {
+	tasks = append(tasks, templateTasks(n.Pipe)...)
+	tasks = append(tasks, templateTasks(n.List)...)
+	return append(tasks, templateTasks(n.ElseList)...)
+}
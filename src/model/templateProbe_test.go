@@ -0,0 +1,126 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testTemplateConfig struct {
+	URL    string   `json:"url"`
+	Args   []string `json:"args"`
+	Status int      `json:"status"`
+}
+
+// testTemplateFactory creates a probe that returns its configuration as the probing data
+func testTemplateFactory(configuration []byte) (Prober, error) {
+	var config testTemplateConfig
+	if err := json.Unmarshal(configuration, &config); err != nil {
+		return nil, err
+	}
+	if config.URL == "" {
+		return nil, ErrorConfig
+	}
+	p := newTestProbe(func(context.Context) (bool, any) {
+		return true, config
+	})
+	p.Config = config
+	return p, nil
+}
+
+func Test_TemplateProbe(t *testing.T) {
+	configuration := []byte(`{
+		"url": "https://example.com/profile?token={{ .tasks.login.data.capture }}",
+		"args": ["-u", "{{ index .tasks \"get-user\" \"data\" \"user\" }}", "static"],
+		"status": 200
+	}`)
+	p, err := NewTemplateProbe("test", DefaultProbeOptions, configuration, testTemplateFactory)
+	if err != nil {
+		t.Fatalf("template probe should be created: %v", err)
+	}
+	tasks := map[string]bool{}
+	for _, name := range p.Tasks() {
+		tasks[name] = true
+	}
+	if len(tasks) != 2 || !tasks["login"] || !tasks["get-user"] {
+		t.Fatalf("referenced tasks should be login and get-user, got %v", p.Tasks())
+	}
+	if r := p.Result(); r.Configuration != nil || r.Name != "test" {
+		t.Fatalf("configuration templates shouldn't be returned before the first run, got %+v", r)
+	}
+
+	vars := map[string]any{"tasks": map[string]any{
+		"login":    map[string]any{"data": map[string]any{"capture": "secret"}},
+		"get-user": map[string]any{"data": map[string]any{"user": "admin"}},
+	}}
+	if err = p.Render(vars); err != nil {
+		t.Fatalf("configuration should be rendered: %v", err)
+	}
+	ctx := ContextWithLogger(context.Background(), NewChainLogger(DefaultLogger, "template"))
+	if !p.Start(ctx) {
+		t.Fatal("probe should return true")
+	}
+	expected := testTemplateConfig{
+		URL:    "https://example.com/profile?token=secret",
+		Args:   []string{"-u", "admin", "static"},
+		Status: 200,
+	}
+	config, ok := p.Result().Configuration.(testTemplateConfig)
+	if !ok || config.URL != expected.URL || config.Args[1] != expected.Args[1] || config.Status != expected.Status {
+		t.Fatalf("rendered configuration should be %+v, got %+v", expected, p.Result().Configuration)
+	}
+
+	// the referenced value is missing
+	err = p.Render(map[string]any{"tasks": map[string]any{"login": map[string]any{"data": map[string]any{}}}})
+	if !errors.Is(err, ErrTemplate) {
+		t.Fatalf("render error should be returned, got %v", err)
+	}
+	if p.Start(ctx) {
+		t.Fatal("probe should return false after render error")
+	}
+	if r := p.Result(); r.Success || !r.Completed() || r.Configuration != nil {
+		t.Fatal("failed result without the configuration templates should be returned after render error")
+	}
+
+	if _, err = NewTemplateProbe("test", DefaultProbeOptions, []byte(`{"url": "{{ .tasks.login"}`), testTemplateFactory); err == nil {
+		t.Fatal("template parse error should be returned")
+	}
+}
+
+func Test_ScriptTemplateProbe(t *testing.T) {
+	login := newTestProbe(func(context.Context) (bool, any) {
+		return true, map[string]string{"capture": "secret"}
+	})
+	profile, err := NewTemplateProbe("test", DefaultProbeOptions,
+		[]byte(`{"url": "https://example.com/?token={{ .tasks.login.data.capture }}"}`), testTemplateFactory)
+	if err != nil {
+		t.Fatalf("template probe should be created: %v", err)
+	}
+	broken, err := NewTemplateProbe("test", DefaultProbeOptions,
+		[]byte(`{"url": "https://example.com/?token={{ .tasks.login.data.missing }}"}`), testTemplateFactory)
+	if err != nil {
+		t.Fatalf("template probe should be created: %v", err)
+	}
+
+	s := &Script{}
+	// the templated tasks are in the same cgroup, but wait for the referenced task
+	s.AddTask(NewTask("profile", "", TaskMetric{}, profile))
+	s.AddTask(NewTask("broken", "", TaskMetric{}, broken))
+	s.AddTask(NewTask("login", "", TaskMetric{}, login))
+	if err = s.ResolveDependencies(); err != nil {
+		t.Fatalf("dependencies should be resolved: %v", err)
+	}
+	s.Run(context.Background())
+
+	r := s.ResultFinished()
+	if !r.Tasks[0].Success {
+		t.Fatal("task with rendered configuration should succeed")
+	}
+	if c, _ := r.Tasks[0].Probe.Configuration.(testTemplateConfig); c.URL != "https://example.com/?token=secret" {
+		t.Fatalf("rendered configuration should be returned, got %+v", r.Tasks[0].Probe.Configuration)
+	}
+	if r.Tasks[1].Success || len(r.Tasks[1].Attempts) != 1 || r.Tasks[1].Attempts[0].Error == "" {
+		t.Fatalf("task should fail with the render error, got %+v", r.Tasks[1])
+	}
+}
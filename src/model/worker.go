@@ -13,36 +13,112 @@ const (
 	EStatusFinished EStatus = "finished"
 	EStatusNew      EStatus = ""
 	EStatusRunning  EStatus = "running"
+	EStatusTimeout  EStatus = "timeout"
+	EStatusSkipped  EStatus = "skipped"
 )
 
+var ErrTimeout = errors.New("timeout")
+
+// ErrStaleRun is returned if the run has been replaced by the next one
+var ErrStaleRun = errors.New("the run is over")
+
 type Worker struct {
 	prevResult Result
+	prevStatus EStatus
 	curResult  Result
 	EStatus
 	sync.Mutex
 }
 
 func (s *Worker) EStatusRun() (err error) {
+	_, err = s.EStatusStart()
+	return
+}
+
+// EStatusStart is EStatusRun which returns the run counter of the started run,
+// the run methods with the counter don't change the later runs
+func (s *Worker) EStatusStart() (run uint, err error) {
 	s.Lock()
 	defer s.Unlock()
 	if s.EStatus == EStatusRunning {
-		return errors.New("already " + string(s.EStatus))
+		return 0, errors.New("already " + string(s.EStatus))
 	}
 	s.EStatus = EStatusRunning
 	s.curResult.PrepareToStart()
-	return
+	return s.curResult.RunCounter, nil
 }
 
 func (s *Worker) EStatusFinish(succ bool) (err error) {
 	s.Lock()
 	defer s.Unlock()
+	return s.finish(succ)
+}
+
+// EStatusFinishRun is EStatusFinish of the run started by EStatusStart
+func (s *Worker) EStatusFinishRun(run uint, succ bool) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.curResult.RunCounter != run {
+		return ErrStaleRun
+	}
+	return s.finish(succ)
+}
+
+// EStatusTimeoutRun is EStatusTimeout of the run started by EStatusStart
+func (s *Worker) EStatusTimeoutRun(run uint) {
+	s.Lock()
+	defer s.Unlock()
+	if s.curResult.RunCounter == run && s.EStatus == EStatusRunning {
+		s.end(EStatusTimeout, false)
+	}
+}
+
+// IsRun returns true if the run started by EStatusStart is the current one
+func (s *Worker) IsRun(run uint) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.curResult.RunCounter == run
+}
+
+func (s *Worker) finish(succ bool) (err error) {
+	if s.EStatus == EStatusTimeout {
+		return ErrTimeout
+	}
 	if s.EStatus != EStatusRunning {
 		return fmt.Errorf("can't switch from status %v to %v", string(s.EStatus), string(EStatusFinished))
 	}
-	s.EStatus = EStatusFinished
+	s.end(EStatusFinished, succ)
+	return
+}
+
+// EStatusTimeout interrupts the running worker with a failed result
+func (s *Worker) EStatusTimeout() {
+	s.Lock()
+	defer s.Unlock()
+	if s.EStatus != EStatusRunning {
+		return
+	}
+	s.end(EStatusTimeout, false)
+}
+
+// EStatusSkip records a run that hasn't been started with a failed result and the reason
+func (s *Worker) EStatusSkip(reason string) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.EStatus == EStatusRunning {
+		return errors.New("already " + string(s.EStatus))
+	}
+	s.curResult.PrepareToStart()
+	s.curResult.Reason = reason
+	s.end(EStatusSkipped, false)
+	return
+}
+
+func (s *Worker) end(status EStatus, succ bool) {
+	s.EStatus = status
 	s.curResult.End(succ)
 	s.prevResult = s.curResult
-	return
+	s.prevStatus = s.EStatus
 }
 
 func (s *Worker) Duration() time.Duration {
@@ -68,3 +144,13 @@ func (s *Worker) ResultFinished() (result Result) {
 	result = s.prevResult
 	return
 }
+
+// StatusFinished returns a status the worker had at the last finish
+func (s *Worker) StatusFinished() (status EStatus) {
+	s.Lock()
+	defer s.Unlock()
+	if s.prevResult.Completed() {
+		status = s.prevStatus
+	}
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/go-cmd/cmd"
 	"log"
+	"os"
 	"strings"
 	"time"
 )
@@ -20,7 +21,7 @@ type Probe struct {
 
 var name = "cmd"
 var ErrTimeout = errors.New("timeout")
-var ErrUnexpectedExit = errors.New("cmd exited unexpectedly")
+var ErrUnexpectedExit = model.NewReasonError(model.ReasonUnexpectedExit, errors.New("cmd exited unexpectedly"))
 
 func init() {
 	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
@@ -51,6 +52,12 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 		} else {
 			c.Log("[%v] OK, %vms", c.Cmd, c.Duration().Milliseconds())
 		}
+		if !succ {
+			if err == nil {
+				err = model.ErrUnexpectedSuccess
+			}
+			c.SetTargetError(c.Cmd, err)
+		}
 	}()
 
 	if c.cmd != nil {
@@ -60,8 +67,19 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 	}
 
 	c.cmd = cmd.NewCmdOptions(cmd.Options{Buffered: true, Streaming: true}, c.Cmd, c.Args...)
+	if len(c.Env) > 0 {
+		c.cmd.Env = os.Environ()
+		for k, v := range c.Env {
+			c.cmd.Env = append(c.cmd.Env, k+"="+v)
+		}
+	}
 
-	status := c.cmd.Start()
+	var status <-chan cmd.Status
+	if c.Stdin != "" {
+		status = c.cmd.StartWithStdin(strings.NewReader(c.Stdin))
+	} else {
+		status = c.cmd.Start()
+	}
 
 	timer := time.After(c.Timeout)
 	var interrupted error
@@ -136,21 +154,26 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 	}
 
 	if finished.Exit != c.ExitCode {
-		err = fmt.Errorf("wrong exit code %v", finished.Exit)
+		if finished.Error != nil {
+			// the command can't be started
+			err = fmt.Errorf("wrong exit code %v: %w", finished.Exit, finished.Error)
+		} else {
+			err = model.NewReasonError(model.ReasonExitCode, fmt.Errorf("wrong exit code %v", finished.Exit))
+		}
 	}
 	regexMatch, regexCondition, capture, captureMatch, captureCondition := c.checkStdout(finished.Stdout)
 	if finished.Exit == c.ExitCode && c.regexp != nil && !regexCondition && c.regexRequired() {
 		if c.RegexInvert {
-			err = fmt.Errorf("stdout matches forbidden regex")
+			err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("stdout matches forbidden regex"))
 		} else {
-			err = fmt.Errorf("stdout doesn't match regex")
+			err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("stdout doesn't match regex"))
 		}
 	}
 	if finished.Exit == c.ExitCode && c.captureRegexp != nil && !captureCondition {
 		if c.CaptureRegexInvert {
-			err = fmt.Errorf("capture matches forbidden regex")
+			err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("capture matches forbidden regex"))
 		} else {
-			err = fmt.Errorf("capture doesn't match regex")
+			err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("capture doesn't match regex"))
 		}
 	}
 	regexSuccess := c.regexp == nil || !c.regexRequired() || regexCondition
@@ -153,6 +153,7 @@ func Test_RunnerRegex(t *testing.T) {
 		name           string
 		config         Config
 		expectedResult bool
+		expectedReason model.ErrorReason
 	}
 
 	cases := []testCase{
@@ -173,6 +174,7 @@ func Test_RunnerRegex(t *testing.T) {
 				Regex: `maintenance`,
 			},
 			expectedResult: false,
+			expectedReason: model.ReasonRegexMismatch,
 		},
 		{
 			name: "inverted stdout regex fails on match",
@@ -183,6 +185,7 @@ func Test_RunnerRegex(t *testing.T) {
 				RegexInvert: true,
 			},
 			expectedResult: false,
+			expectedReason: model.ReasonRegexMismatch,
 		},
 		{
 			name: "inverted stdout regex succeeds without match",
@@ -203,6 +206,7 @@ func Test_RunnerRegex(t *testing.T) {
 				Regex:    `version`,
 			},
 			expectedResult: false,
+			expectedReason: model.ReasonExitCode,
 		},
 	}
 
@@ -216,6 +220,9 @@ func Test_RunnerRegex(t *testing.T) {
 			if p.Start(ctx) != c.expectedResult {
 				t.Fatalf("probe should return %v", c.expectedResult)
 			}
+			if reason := p.Result().Errors[c.config.Cmd].Reason; reason != c.expectedReason {
+				t.Fatalf("error reason should be %q, got %q", c.expectedReason, reason)
+			}
 			p.Finish(ctx)
 		})
 	}
@@ -564,3 +571,31 @@ func Test_ConstructorWrongRegexCaptureGroup(t *testing.T) {
 		t.Fatal("constructor should return an error for invalid regexCaptureGroup")
 	}
 }
+
+func Test_RunnerEnvStdin(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	p, err := constructor.NewProbe(
+		model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true},
+		Config{
+			Cmd:   "sh",
+			Args:  []string{"-c", `read line; echo "$PROBE_ENV $line $HOME"`},
+			Env:   map[string]string{"PROBE_ENV": "env"},
+			Stdin: "stdin\n",
+			Regex: `^env stdin /`,
+		},
+	)
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "env stdin"))
+	if !p.Start(ctx) {
+		t.Fatal("the command should get the environment variables and the standard input")
+	}
+	p.Finish(ctx)
+}
@@ -12,12 +12,14 @@ type Config struct {
 	Args               []string
 	ExitCode           int
 	LogDump            bool
-	Regex              string `json:"regex,omitempty"`
-	RegexInvert        bool   `json:"regexInvert,omitempty"`
-	RegexRequired      *bool  `json:"regexRequired,omitempty"`
-	RegexCaptureGroup  int    `json:"regexCaptureGroup,omitempty"`
-	CaptureRegex       string `json:"captureRegex,omitempty"`
-	CaptureRegexInvert bool   `json:"captureRegexInvert,omitempty"`
+	Env                map[string]string `json:"env,omitempty"`   // additional environment variables
+	Stdin              string            `json:"stdin,omitempty"` // standard input of the command
+	Regex              string            `json:"regex,omitempty"`
+	RegexInvert        bool              `json:"regexInvert,omitempty"`
+	RegexRequired      *bool             `json:"regexRequired,omitempty"`
+	RegexCaptureGroup  int               `json:"regexCaptureGroup,omitempty"`
+	CaptureRegex       string            `json:"captureRegex,omitempty"`
+	CaptureRegexInvert bool              `json:"captureRegexInvert,omitempty"`
 	regexp             *regexp.Regexp
 	captureRegexp      *regexp.Regexp
 }
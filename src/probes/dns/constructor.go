@@ -0,0 +1,103 @@
+package dns
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/miekg/dns"
+)
+
+type constructor struct {
+	probefactory.BaseConstructor
+}
+
+func (c constructor) NewProbe(options model.ProbeOptions, configuration any) (p model.Prober, err error) {
+	var config Config
+	if config, err = c.configuration(configuration); err != nil {
+		return
+	}
+
+	if len(config.Names) == 0 || config.Names[0] == "" {
+		return nil, model.ErrorConfig
+	}
+
+	return New(options, config), nil
+}
+
+func (c constructor) NewProbeConfiguration() any {
+	return c.SetConfigDefaults(&Config{})
+}
+
+// configuration casts configuration of any type to Config struct
+func (c constructor) configuration(conf any) (configuration Config, err error) {
+	if conf == nil {
+		err = model.ErrorConfig
+		return
+	}
+
+	switch v := conf.(type) {
+	case *Config:
+		configuration = *v
+	case Config:
+		configuration = v
+	case string:
+		names := regexp.MustCompile("\\s*,\\s*").Split(v, -1)
+		newConfig := c.NewProbeConfiguration().(*Config)
+		newConfig.Names = names
+		configuration = *newConfig
+	default:
+		err = model.ErrorConfig
+		return
+	}
+
+	err = configuration.validate()
+	return
+}
+
+func (c *Config) validate() (err error) {
+	_ = defaults.Set(c)
+
+	c.Protocol = strings.ToLower(c.Protocol)
+	if c.Protocol != "udp" && c.Protocol != "tcp" {
+		return fmt.Errorf("unsupported protocol %v", c.Protocol)
+	}
+
+	qType, ok := dns.StringToType[strings.ToUpper(c.Type)]
+	if !ok || !supportedTypes[qType] {
+		return fmt.Errorf("unsupported record type %v", c.Type)
+	}
+	c.qType = qType
+
+	if c.Match == "" {
+		c.Match = MatchAny
+		if len(c.Expected) > 0 {
+			c.Match = MatchContains
+		}
+	}
+	switch c.Match {
+	case MatchAny, MatchNXDomain:
+	case MatchExact, MatchContains:
+		if len(c.Expected) == 0 {
+			return fmt.Errorf("match %v requires expected answers", c.Match)
+		}
+	case MatchRegex:
+		if len(c.Expected) == 0 {
+			return fmt.Errorf("match %v requires expected answers", c.Match)
+		}
+		c.expectedRegexp = make([]*regexp.Regexp, 0, len(c.Expected))
+		for _, e := range c.Expected {
+			r, err := regexp.Compile(e)
+			if err != nil {
+				return fmt.Errorf("wrong regex: %w", err)
+			}
+			c.expectedRegexp = append(c.expectedRegexp, r)
+		}
+	default:
+		return fmt.Errorf("unsupported match %v", c.Match)
+	}
+	return
+}
@@ -0,0 +1,310 @@
+package dns
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type Probe struct {
+	model.ProbeHandler
+	Config    `json:"config"`
+	configErr error // the probe fails every run if the config isn't valid
+}
+
+type Config struct {
+	Server         string   `json:"server,omitempty"`       // host[:port], the first resolv.conf nameserver is used by default
+	Protocol       string   `json:"protocol" default:"udp"` // udp | tcp
+	Type           string   `json:"type" default:"A"`       // A | AAAA | CNAME | MX | TXT | SRV | PTR
+	Names          []string `json:"names"`
+	Expected       []string `json:"expected,omitempty"`
+	Match          string   `json:"match,omitempty"` // any | exact | contains | regex | nxdomain
+	qType          uint16
+	expectedRegexp []*regexp.Regexp
+}
+
+type ResultData struct {
+	Timings map[string]int    `json:"timings"`
+	Answers map[string]int    `json:"answers"`
+	Rcode   map[string]string `json:"rcode"`
+}
+
+const (
+	MatchAny       = "any"
+	MatchExact     = "exact"
+	MatchContains  = "contains"
+	MatchRegex     = "regex"
+	MatchNXDomain  = "nxdomain"
+	ResolvConfPath = "/etc/resolv.conf"
+)
+
+var name = "dns"
+
+var (
+	ErrTimeout          = errors.New("timeout")
+	ErrNoAnswer         = model.NewReasonError(model.ReasonMismatch, errors.New("no answer"))
+	ErrUnexpectedAnswer = model.NewReasonError(model.ReasonMismatch, errors.New("unexpected answer"))
+)
+
+var supportedTypes = map[uint16]bool{
+	dns.TypeA:     true,
+	dns.TypeAAAA:  true,
+	dns.TypeCNAME: true,
+	dns.TypeMX:    true,
+	dns.TypeTXT:   true,
+	dns.TypeSRV:   true,
+	dns.TypePTR:   true,
+}
+
+func init() {
+	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
+}
+
+// New creates the probe with the config filled with defaults, the probe with an invalid config fails every run
+func New(options model.ProbeOptions, config Config) *Probe {
+	p := Probe{}
+	p.ProbeOptions = options
+	p.Name = name
+	if err := config.validate(); err != nil {
+		p.configErr = fmt.Errorf("%w: %w", model.ErrorConfig, err)
+	}
+	p.Config = config
+	p.ProbeHandler.Config = config
+	p.SetRunner(p.Runner)
+	return &p
+}
+
+//nolint:funlen
+func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
+	var timings model.Timings
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	answers := make(map[string]int)
+	rcodes := make(map[string]string)
+
+	if c.configErr != nil {
+		c.Log("%v", c.configErr)
+		return false, nil
+	}
+	server, err := c.server()
+	if err != nil {
+		c.Log("%v", err)
+		return false, nil
+	}
+
+	done := 0
+	for _, n := range c.Names {
+		wg.Add(1)
+		go func(s string) {
+			t := time.Now()
+			var err error
+			defer func() {
+				dur := time.Since(t)
+				if e := recover(); e != nil {
+					err = fmt.Errorf("panic occurred: %v", e)
+				}
+
+				if err != nil {
+					c.Log("[%v] %v, %vms", s, err, dur.Milliseconds())
+					if c.Expect {
+						c.SetTargetError(s, err)
+					} else {
+						mutex.Lock()
+						done++
+						mutex.Unlock()
+					}
+				} else {
+					if c.Expect {
+						mutex.Lock()
+						done++
+						mutex.Unlock()
+					} else {
+						c.SetTargetError(s, model.ErrUnexpectedSuccess)
+					}
+					c.Log("[%v] OK, %vms", s, dur.Milliseconds())
+				}
+				wg.Done()
+			}()
+
+			r, err := c.query(ctx, server, s)
+			if err != nil {
+				return
+			}
+			timings.Set(s, time.Since(t))
+
+			values := answerValues(r, c.qType)
+			mutex.Lock()
+			answers[s] = len(values)
+			rcodes[s] = dns.RcodeToString[r.Rcode]
+			mutex.Unlock()
+
+			err = c.checkAnswer(r.Rcode, values)
+		}(n)
+	}
+	wg.Wait()
+	succ = done == len(c.Names)
+	resultObject = ResultData{
+		Timings: timings.TimingsMs(),
+		Answers: answers,
+		Rcode:   rcodes,
+	}
+	return
+}
+
+// server returns the nameserver address in host:port form
+func (c *Probe) server() (string, error) {
+	server := c.Server
+	if server == "" {
+		conf, err := dns.ClientConfigFromFile(ResolvConfPath)
+		if err != nil {
+			return "", fmt.Errorf("can't read nameservers from %v: %w", ResolvConfPath, err)
+		}
+		if len(conf.Servers) == 0 {
+			return "", fmt.Errorf("no nameservers in %v", ResolvConfPath)
+		}
+		return net.JoinHostPort(conf.Servers[0], conf.Port), nil
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server, nil
+}
+
+func (c *Probe) query(ctx context.Context, server string, qName string) (r *dns.Msg, err error) {
+	if c.qType == dns.TypePTR && net.ParseIP(qName) != nil {
+		if qName, err = dns.ReverseAddr(qName); err != nil {
+			return
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(qName), c.qType)
+	m.RecursionDesired = true
+
+	client := dns.Client{Net: c.Protocol, Timeout: c.Timeout}
+	r, _, err = client.ExchangeContext(ctx, m, server)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			err = ErrTimeout
+		} else {
+			err = fmt.Errorf("dns error %w", err)
+		}
+	}
+	return
+}
+
+func (c *Probe) checkAnswer(rcode int, values []string) error {
+	if c.Match == MatchNXDomain {
+		if rcode != dns.RcodeNameError {
+			return fmt.Errorf("expected NXDOMAIN, got %v", dns.RcodeToString[rcode])
+		}
+		return nil
+	}
+	if rcode != dns.RcodeSuccess {
+		return fmt.Errorf("response code %v", dns.RcodeToString[rcode])
+	}
+	if len(values) == 0 {
+		return ErrNoAnswer
+	}
+
+	switch c.Match {
+	case MatchExact:
+		if !equalSets(values, c.Expected) {
+			return fmt.Errorf("%w %v", ErrUnexpectedAnswer, values)
+		}
+	case MatchContains:
+		for _, e := range c.Expected {
+			if !containsValue(values, e) {
+				return fmt.Errorf("%w %v, %v is missing", ErrUnexpectedAnswer, values, e)
+			}
+		}
+	case MatchRegex:
+		for _, r := range c.expectedRegexp {
+			matched := false
+			for _, v := range values {
+				if r.MatchString(v) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("%w %v, no answer matches %v", ErrUnexpectedAnswer, values, r)
+			}
+		}
+	}
+	return nil
+}
+
+// answerValues returns string representation of answer records of a requested type
+func answerValues(r *dns.Msg, qType uint16) (values []string) {
+	for _, rr := range r.Answer {
+		if rr.Header().Rrtype != qType {
+			continue
+		}
+		switch v := rr.(type) {
+		case *dns.A:
+			values = append(values, v.A.String())
+		case *dns.AAAA:
+			values = append(values, v.AAAA.String())
+		case *dns.CNAME:
+			values = append(values, normalizeName(v.Target))
+		case *dns.MX:
+			values = append(values, normalizeName(v.Mx))
+		case *dns.TXT:
+			values = append(values, strings.Join(v.Txt, ""))
+		case *dns.SRV:
+			values = append(values, normalizeName(v.Target)+":"+strconv.Itoa(int(v.Port)))
+		case *dns.PTR:
+			values = append(values, normalizeName(v.Ptr))
+		}
+	}
+	return
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.TrimSuffix(s, "."))
+}
+
+func containsValue(values []string, v string) bool {
+	for _, e := range values {
+		if strings.EqualFold(e, normalizeExpected(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalSets(values []string, expected []string) bool {
+	a := make([]string, 0, len(values))
+	for _, v := range values {
+		a = append(a, strings.ToLower(v))
+	}
+	b := make([]string, 0, len(expected))
+	for _, v := range expected {
+		b = append(b, strings.ToLower(normalizeExpected(v)))
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\n") == strings.Join(b, "\n")
+}
+
+// normalizeExpected removes the trailing dot from expected domain names,
+// IP addresses are converted to the canonical form
+func normalizeExpected(v string) string {
+	if ip := net.ParseIP(v); ip != nil {
+		return ip.String()
+	}
+	return strings.TrimSuffix(v, ".")
+}
@@ -0,0 +1,299 @@
+package dns
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var testZone = map[uint16]map[string][]string{
+	dns.TypeA: {
+		"www.example.test.": {"www.example.test. 60 IN A 10.0.0.1", "www.example.test. 60 IN A 10.0.0.2"},
+	},
+	dns.TypeAAAA: {
+		"www.example.test.": {"www.example.test. 60 IN AAAA 2001:db8::1"},
+	},
+	dns.TypeCNAME: {
+		"alias.example.test.": {"alias.example.test. 60 IN CNAME www.example.test."},
+	},
+	dns.TypeMX: {
+		"example.test.": {"example.test. 60 IN MX 10 mail.example.test."},
+	},
+	dns.TypeTXT: {
+		"example.test.": {`example.test. 60 IN TXT "v=spf1 -all"`},
+	},
+	dns.TypeSRV: {
+		"_sip._udp.example.test.": {"_sip._udp.example.test. 60 IN SRV 10 5 5060 sip.example.test."},
+	},
+	dns.TypePTR: {
+		"1.0.0.10.in-addr.arpa.": {"1.0.0.10.in-addr.arpa. 60 IN PTR www.example.test."},
+	},
+}
+
+// testStartServer starts an in-process dns server that answers from testZone
+// on udp and tcp using the same port
+func testStartServer(t *testing.T) string {
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		found := false
+		for _, names := range testZone {
+			if _, ok := names[q.Name]; ok {
+				found = true
+			}
+		}
+		if !found {
+			m.SetRcode(r, dns.RcodeNameError)
+		}
+		for _, record := range testZone[q.Qtype][q.Name] {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				t.Errorf("wrong test record %v: %v", record, err)
+				continue
+			}
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp failed: %v", err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("listen tcp failed: %v", err)
+	}
+	udpServer := &dns.Server{PacketConn: pc, Handler: handler}
+	tcpServer := &dns.Server{Listener: l, Handler: handler}
+	go func() { _ = udpServer.ActivateAndServe() }()
+	go func() { _ = tcpServer.ActivateAndServe() }()
+	t.Cleanup(func() {
+		_ = udpServer.Shutdown()
+		_ = tcpServer.Shutdown()
+	})
+	return pc.LocalAddr().String()
+}
+
+func Test_Runner(t *testing.T) {
+	server := testStartServer(t)
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	defOptions := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	type testCase struct {
+		name           string
+		config         Config
+		options        model.ProbeOptions
+		expectedResult bool
+	}
+
+	cases := []testCase{
+		{
+			"A record resolves",
+			Config{Server: server, Names: []string{"www.example.test"}},
+			defOptions,
+			true,
+		},
+		{
+			"A record resolves over tcp",
+			Config{Server: server, Protocol: "tcp", Names: []string{"www.example.test"}},
+			defOptions,
+			true,
+		},
+		{
+			"A record exact set",
+			Config{Server: server, Names: []string{"www.example.test"}, Expected: []string{"10.0.0.2", "10.0.0.1"}, Match: MatchExact},
+			defOptions,
+			true,
+		},
+		{
+			"A record exact set mismatch",
+			Config{Server: server, Names: []string{"www.example.test"}, Expected: []string{"10.0.0.1"}, Match: MatchExact},
+			defOptions,
+			false,
+		},
+		{
+			"A record contains by default",
+			Config{Server: server, Names: []string{"www.example.test"}, Expected: []string{"10.0.0.2"}},
+			defOptions,
+			true,
+		},
+		{
+			"A record doesn't contain",
+			Config{Server: server, Names: []string{"www.example.test"}, Expected: []string{"10.0.0.3"}},
+			defOptions,
+			false,
+		},
+		{
+			"AAAA record",
+			Config{Server: server, Type: "AAAA", Names: []string{"www.example.test"}, Expected: []string{"2001:db8:0::1"}},
+			defOptions,
+			true,
+		},
+		{
+			"CNAME record",
+			Config{Server: server, Type: "CNAME", Names: []string{"alias.example.test"}, Expected: []string{"www.example.test."}},
+			defOptions,
+			true,
+		},
+		{
+			"MX record",
+			Config{Server: server, Type: "mx", Names: []string{"example.test"}, Expected: []string{"mail.example.test"}},
+			defOptions,
+			true,
+		},
+		{
+			"TXT record regex",
+			Config{Server: server, Type: "TXT", Names: []string{"example.test"}, Expected: []string{`^v=spf1 `}, Match: MatchRegex},
+			defOptions,
+			true,
+		},
+		{
+			"SRV record",
+			Config{Server: server, Type: "SRV", Names: []string{"_sip._udp.example.test"}, Expected: []string{"sip.example.test:5060"}},
+			defOptions,
+			true,
+		},
+		{
+			"PTR record for IP address",
+			Config{Server: server, Type: "PTR", Names: []string{"10.0.0.1"}, Expected: []string{"www.example.test"}},
+			defOptions,
+			true,
+		},
+		{
+			"unknown name fails",
+			Config{Server: server, Names: []string{"www.example.test", "missing.example.test"}},
+			defOptions,
+			false,
+		},
+		{
+			"NXDOMAIN expected",
+			Config{Server: server, Names: []string{"missing.example.test"}, Match: MatchNXDomain},
+			defOptions,
+			true,
+		},
+		{
+			"NXDOMAIN expected but name exists",
+			Config{Server: server, Names: []string{"www.example.test"}, Match: MatchNXDomain},
+			defOptions,
+			false,
+		},
+		{
+			"no records of requested type",
+			Config{Server: server, Type: "MX", Names: []string{"www.example.test"}},
+			defOptions,
+			false,
+		},
+		{
+			"expect false",
+			Config{Server: server, Names: []string{"missing.example.test"}},
+			model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: false},
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := constructor.NewProbe(c.options, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if p.Start(ctx) != c.expectedResult {
+				t.Fatalf("probe should return %v", c.expectedResult)
+			}
+		})
+	}
+}
+
+func Test_RunnerResultData(t *testing.T) {
+	server := testStartServer(t)
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	p, err := constructor.NewProbe(
+		model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true},
+		Config{Server: server, Names: []string{"www.example.test", "missing.example.test"}},
+	)
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "result data"))
+	_ = p.Start(ctx)
+
+	data, ok := p.Result().Data.(ResultData)
+	if !ok {
+		t.Fatalf("probe data should be ResultData, got %T", p.Result().Data)
+	}
+	if data.Answers["www.example.test"] != 2 {
+		t.Fatalf("answer count should be 2, got %v", data.Answers["www.example.test"])
+	}
+	if data.Answers["missing.example.test"] != 0 {
+		t.Fatalf("answer count should be 0, got %v", data.Answers["missing.example.test"])
+	}
+	if data.Rcode["missing.example.test"] != "NXDOMAIN" {
+		t.Fatalf("rcode should be NXDOMAIN, got %v", data.Rcode["missing.example.test"])
+	}
+	if _, ok = data.Timings["www.example.test"]; !ok {
+		t.Fatal("timing should be exported")
+	}
+}
+
+func Test_Constructor(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	options := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	p, err := constructor.NewProbe(options, "example.com, example.org")
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+	if names := p.(*Probe).Names; len(names) != 2 || names[1] != "example.org" {
+		t.Fatalf("names should be parsed from string, got %v", names)
+	}
+
+	wrongConfigs := []Config{
+		{Names: []string{"example.com"}, Type: "NS"},
+		{Names: []string{"example.com"}, Protocol: "sctp"},
+		{Names: []string{"example.com"}, Match: MatchExact},
+		{Names: []string{"example.com"}, Match: MatchRegex, Expected: []string{"["}},
+		{Names: []string{"example.com"}, Match: "unknown"},
+		{},
+	}
+	for i, c := range wrongConfigs {
+		if _, err = constructor.NewProbe(options, c); err == nil {
+			t.Errorf("constructor should return an error for config %v", i)
+		}
+	}
+}
+
+func Test_New(t *testing.T) {
+	options := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	// the probe created without the constructor gets the config defaults
+	p := New(options, Config{Names: []string{"example.com"}})
+	if p.Protocol != "udp" || p.Type != "A" || p.qType != dns.TypeA || p.Match != MatchAny || p.configErr != nil {
+		t.Fatalf("config defaults should be set, got %+v", p.Config)
+	}
+
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "new"))
+	if p = New(options, Config{Names: []string{"example.com"}, Type: "NS"}); p.Start(ctx) {
+		t.Fatal("probe with an invalid config should fail")
+	}
+}
@@ -63,6 +63,11 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 		if err != nil {
 			c.Log("[%v] %v, %vms", host, err, c.Duration().Milliseconds())
 			c.SetError(err)
+			target := host
+			if target == "" {
+				target = c.ConfigFile
+			}
+			c.SetTargetError(target, err)
 		} else {
 			c.Log("[%v] OK, %vms", host, c.Duration().Milliseconds())
 		}
@@ -97,7 +102,7 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 	}
 
 	if host == "" {
-		err = errors.New("can't get remote addr from openvpn configuration")
+		err = model.NewReasonError(model.ReasonConfig, errors.New("can't get remote addr from openvpn configuration"))
 		return
 	}
 
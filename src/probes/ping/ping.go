@@ -65,6 +65,8 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 						mutex.Lock()
 						done++
 						mutex.Unlock()
+					} else {
+						c.SetTargetError(s, err)
 					}
 				} else {
 					timings.Set(s, dur)
@@ -72,6 +74,8 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 						mutex.Lock()
 						done++
 						mutex.Unlock()
+					} else {
+						c.SetTargetError(s, model.ErrUnexpectedSuccess)
 					}
 					c.Log("[%v] OK, %vms", s, dur.Milliseconds())
 				}
@@ -2,8 +2,12 @@ package probes
 
 import (
 	_ "boogieman/src/probes/cmd"
+	_ "boogieman/src/probes/dns"
 	_ "boogieman/src/probes/openvpn"
 	_ "boogieman/src/probes/ping"
+	_ "boogieman/src/probes/tcp"
+	_ "boogieman/src/probes/tls"
 	_ "boogieman/src/probes/traceroute"
 	_ "boogieman/src/probes/web"
+	_ "boogieman/src/probes/xraySSConnect"
 )
@@ -0,0 +1,73 @@
+package tcp
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"fmt"
+	"net"
+	"regexp"
+)
+
+type constructor struct {
+	probefactory.BaseConstructor
+}
+
+func (c constructor) NewProbe(options model.ProbeOptions, configuration any) (p model.Prober, err error) {
+	var config Config
+	if config, err = c.configuration(configuration); err != nil {
+		return
+	}
+
+	if len(config.Targets) == 0 || config.Targets[0] == "" {
+		return nil, model.ErrorConfig
+	}
+	for _, t := range config.Targets {
+		if _, _, e := net.SplitHostPort(t); e != nil {
+			return nil, fmt.Errorf("wrong target %v: %w", t, e)
+		}
+	}
+
+	return New(options, config), nil
+}
+
+func (c constructor) NewProbeConfiguration() any {
+	return c.SetConfigDefaults(&Config{})
+}
+
+// configuration casts configuration of any type to Config struct
+func (c constructor) configuration(conf any) (configuration Config, err error) {
+	if conf == nil {
+		err = model.ErrorConfig
+		return
+	}
+
+	switch v := conf.(type) {
+	case *Config:
+		configuration = *v
+	case Config:
+		configuration = v
+	case string:
+		targets := regexp.MustCompile("\\s*,\\s*").Split(v, -1)
+		newConfig := c.NewProbeConfiguration().(*Config)
+		newConfig.Targets = targets
+		configuration = *newConfig
+	default:
+		err = model.ErrorConfig
+		return
+	}
+
+	err = configuration.compileRegex()
+	return
+}
+
+func (c *Config) compileRegex() error {
+	if c.Banner == "" {
+		return nil
+	}
+	r, err := regexp.Compile(c.Banner)
+	if err != nil {
+		return fmt.Errorf("wrong banner regex: %w", err)
+	}
+	c.bannerRegexp = r
+	return nil
+}
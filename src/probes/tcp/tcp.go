@@ -0,0 +1,182 @@
+package tcp
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/creasty/defaults"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Probe struct {
+	model.ProbeHandler
+	Config    `json:"config"`
+	configErr error // the probe fails every run if the config isn't valid
+}
+
+type Config struct {
+	Targets            []string // list of host:port
+	Banner             string   `json:"banner,omitempty"` // regex the server greeting should match
+	BannerSize         int      `json:"bannerSize,omitempty" default:"1024"`
+	TLS                bool     `json:"tls,omitempty"`        // perform TLS handshake after connect
+	ServerName         string   `json:"serverName,omitempty"` // TLS SNI, the target host is used by default
+	InsecureSkipVerify bool     `json:"insecureSkipVerify,omitempty"`
+	bannerRegexp       *regexp.Regexp
+}
+
+var name = "tcp"
+
+var (
+	ErrTimeout        = errors.New("timeout")
+	ErrBannerMismatch = model.NewReasonError(model.ReasonRegexMismatch, errors.New("banner doesn't match regex"))
+)
+
+func init() {
+	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
+}
+
+// New creates the probe with the config filled with defaults, the probe with an invalid config fails every run
+func New(options model.ProbeOptions, config Config) *Probe {
+	p := Probe{}
+	p.ProbeOptions = options
+	p.Name = name
+	_ = defaults.Set(&config)
+	if err := config.compileRegex(); err != nil {
+		p.configErr = fmt.Errorf("%w: %w", model.ErrorConfig, err)
+	}
+	p.Config = config
+	p.ProbeHandler.Config = config
+	p.SetRunner(p.Runner)
+	return &p
+}
+
+func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
+	if c.configErr != nil {
+		c.Log("%v", c.configErr)
+		return false, nil
+	}
+	var timings model.Timings
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	done := 0
+	for _, target := range c.Targets {
+		wg.Add(1)
+		go func(s string) {
+			t := time.Now()
+			var err error
+			defer func() {
+				dur := time.Since(t)
+				if e := recover(); e != nil {
+					err = fmt.Errorf("panic occurred: %v", e)
+				}
+
+				if err != nil {
+					c.Log("[%v] %v, %vms", s, err, dur.Milliseconds())
+					if c.Expect {
+						c.SetTargetError(s, err)
+					} else {
+						mutex.Lock()
+						done++
+						mutex.Unlock()
+					}
+				} else {
+					if c.Expect {
+						mutex.Lock()
+						done++
+						mutex.Unlock()
+					} else {
+						c.SetTargetError(s, model.ErrUnexpectedSuccess)
+					}
+					c.Log("[%v] OK, %vms", s, dur.Milliseconds())
+				}
+				wg.Done()
+			}()
+
+			err = c.check(ctx, s, &timings)
+		}(target)
+	}
+	wg.Wait()
+	succ = done == len(c.Targets)
+	resultObject = timings.TimingsMs()
+	return
+}
+
+// check connects to the target, optionally performs TLS handshake and checks the banner,
+// the connect time is saved to timings on successful connection
+func (c *Probe) check(ctx context.Context, target string, timings *model.Timings) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	t := time.Now()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return wrapError(err)
+	}
+	timings.Set(target, time.Since(t))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if c.TLS {
+		host, _, _ := net.SplitHostPort(target)
+		serverName := c.ServerName
+		if serverName == "" {
+			serverName = host
+		}
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: c.InsecureSkipVerify, //nolint:gosec
+		})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("tls handshake error: %w", wrapError(err))
+		}
+		conn = tlsConn
+	}
+
+	if c.bannerRegexp != nil {
+		err = c.checkBanner(conn)
+	}
+	return
+}
+
+// checkBanner reads the server greeting until the banner regex matches,
+// the connection is closed or bannerSize bytes are read
+func (c *Probe) checkBanner(conn net.Conn) (err error) {
+	buf := make([]byte, 0, c.BannerSize)
+	chunk := make([]byte, c.BannerSize)
+	for len(buf) < c.BannerSize {
+		n, e := conn.Read(chunk[:c.BannerSize-len(buf)])
+		buf = append(buf, chunk[:n]...)
+		if c.bannerRegexp.Match(buf) {
+			return nil
+		}
+		if e != nil {
+			if len(buf) == 0 {
+				return fmt.Errorf("can't read banner: %w", wrapError(e))
+			}
+			break
+		}
+	}
+	return ErrBannerMismatch
+}
+
+func wrapError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() ||
+		strings.Contains(err.Error(), "context deadline exceeded") {
+		return ErrTimeout
+	}
+	return err
+}
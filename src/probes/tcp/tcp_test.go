@@ -0,0 +1,194 @@
+package tcp
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBannerServer starts a tcp server that writes the banner to every accepted connection
+func testBannerServer(t *testing.T, banner string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(banner))
+			_ = conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func testClosedPort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	return addr
+}
+
+func Test_Runner(t *testing.T) {
+	sshAddr := testBannerServer(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	closedAddr := testClosedPort(t)
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer tlsServer.Close()
+	tlsAddr := strings.TrimPrefix(tlsServer.URL, "https://")
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	type testCase struct {
+		name           string
+		config         any
+		options        model.ProbeOptions
+		expectedResult bool
+	}
+
+	defOptions := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+	cases := []testCase{
+		{
+			"connect to open port",
+			Config{Targets: []string{sshAddr}},
+			defOptions,
+			true,
+		},
+		{
+			"connect to targets defined in string",
+			sshAddr + ", " + tlsAddr,
+			defOptions,
+			true,
+		},
+		{
+			"connect to closed port",
+			Config{Targets: []string{sshAddr, closedAddr}},
+			defOptions,
+			false,
+		},
+		{
+			"closed port with expect false",
+			Config{Targets: []string{closedAddr}},
+			model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: false},
+			true,
+		},
+		{
+			"banner matches",
+			Config{Targets: []string{sshAddr}, Banner: `^SSH-2\.0-`},
+			defOptions,
+			true,
+		},
+		{
+			"banner doesn't match",
+			Config{Targets: []string{sshAddr}, Banner: `^220 `},
+			defOptions,
+			false,
+		},
+		{
+			"tls handshake with untrusted certificate",
+			Config{Targets: []string{tlsAddr}, TLS: true},
+			defOptions,
+			false,
+		},
+		{
+			"tls handshake without verification",
+			Config{Targets: []string{tlsAddr}, TLS: true, InsecureSkipVerify: true},
+			defOptions,
+			true,
+		},
+		{
+			"tls handshake with plain tcp server",
+			Config{Targets: []string{sshAddr}, TLS: true, InsecureSkipVerify: true},
+			defOptions,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := constructor.NewProbe(c.options, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if p.Start(ctx) != c.expectedResult {
+				t.Fatalf("probe should return %v", c.expectedResult)
+			}
+		})
+	}
+}
+
+func Test_RunnerTimings(t *testing.T) {
+	addr := testBannerServer(t, "220 smtp.example.com ESMTP\r\n")
+	closedAddr := testClosedPort(t)
+
+	p := New(
+		model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true},
+		Config{Targets: []string{addr, closedAddr}},
+	)
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "timings"))
+	_ = p.Start(ctx)
+
+	data, ok := p.Result().Data.(map[string]int)
+	if !ok {
+		t.Fatalf("probe data should be map[string]int, got %T", p.Result().Data)
+	}
+	if _, ok = data[addr]; !ok {
+		t.Fatal("timing should be exported for connected target")
+	}
+	if _, ok = data[closedAddr]; ok {
+		t.Fatal("timing should not be exported for failed target")
+	}
+}
+
+func Test_NewBanner(t *testing.T) {
+	addr := testBannerServer(t, "220 smtp.example.com ESMTP\r\n")
+	options := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "new"))
+
+	// the banner is checked by the probe created without the constructor
+	if New(options, Config{Targets: []string{addr}, Banner: "^SSH-"}).Start(ctx) {
+		t.Fatal("probe should fail on the banner mismatch")
+	}
+	if !New(options, Config{Targets: []string{addr}, Banner: "^220 "}).Start(ctx) {
+		t.Fatal("probe should succeed on the banner match")
+	}
+	if New(options, Config{Targets: []string{addr}, Banner: "["}).Start(ctx) {
+		t.Fatal("probe with an invalid banner regex should fail")
+	}
+}
+
+func Test_ConstructorWrongConfig(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	options := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	if _, err := constructor.NewProbe(options, Config{Targets: []string{"127.0.0.1"}}); err == nil {
+		t.Fatal("constructor should return an error for target without port")
+	}
+	if _, err := constructor.NewProbe(options, Config{Targets: []string{"127.0.0.1:22"}, Banner: "["}); err == nil {
+		t.Fatal("constructor should return an error for invalid banner regex")
+	}
+	if _, err := constructor.NewProbe(options, ""); err == nil {
+		t.Fatal("constructor should return an error for empty configuration")
+	}
+}
@@ -0,0 +1,83 @@
+package tls
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+)
+
+type constructor struct {
+	probefactory.BaseConstructor
+}
+
+func (c constructor) NewProbe(options model.ProbeOptions, configuration any) (p model.Prober, err error) {
+	var config Config
+	if config, err = c.configuration(configuration); err != nil {
+		return
+	}
+
+	if len(config.Targets) == 0 || config.Targets[0] == "" {
+		return nil, model.ErrorConfig
+	}
+	for _, t := range config.Targets {
+		if _, _, e := net.SplitHostPort(t); e != nil {
+			return nil, fmt.Errorf("wrong target %v: %w", t, e)
+		}
+	}
+
+	return New(options, config), nil
+}
+
+func (c constructor) NewProbeConfiguration() any {
+	return c.SetConfigDefaults(&Config{})
+}
+
+// configuration casts configuration of any type to Config struct
+func (c constructor) configuration(conf any) (configuration Config, err error) {
+	if conf == nil {
+		err = model.ErrorConfig
+		return
+	}
+
+	switch v := conf.(type) {
+	case *Config:
+		configuration = *v
+	case Config:
+		configuration = v
+	case string:
+		targets := regexp.MustCompile("\\s*,\\s*").Split(v, -1)
+		newConfig := c.NewProbeConfiguration().(*Config)
+		newConfig.Targets = targets
+		configuration = *newConfig
+	default:
+		err = model.ErrorConfig
+		return
+	}
+
+	if _, ok := startTLSHandlers[configuration.StartTLS]; !ok && configuration.StartTLS != "" {
+		err = fmt.Errorf("unsupported starttls protocol %v", configuration.StartTLS)
+		return
+	}
+
+	if configuration.CAFile != "" {
+		configuration.rootCAs, err = c.readCAFile(configuration.CAFile)
+	}
+	return
+}
+
+func (c constructor) readCAFile(path string) (pool *x509.CertPool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("can't read CA bundle %v: %w", path, err)
+		return
+	}
+	pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		err = fmt.Errorf("no certificates found in CA bundle %v", path)
+	}
+	return
+}
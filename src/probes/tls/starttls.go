@@ -0,0 +1,177 @@
+package tls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// startTLSHandler upgrades a plain text protocol session to the point where TLS handshake can be started
+type startTLSHandler func(conn net.Conn) error
+
+var startTLSHandlers = map[string]startTLSHandler{
+	"smtp":     startTLSSMTP,
+	"imap":     startTLSIMAP,
+	"ldap":     startTLSLDAP,
+	"postgres": startTLSPostgres,
+}
+
+var ErrStartTLSRejected = errors.New("server rejected STARTTLS")
+
+func startTLSSMTP(conn net.Conn) (err error) {
+	r := bufio.NewReader(conn)
+	if err = smtpExpect(r, "220"); err != nil {
+		return
+	}
+	if _, err = io.WriteString(conn, "EHLO boogieman\r\n"); err != nil {
+		return
+	}
+	if err = smtpExpect(r, "250"); err != nil {
+		return
+	}
+	if _, err = io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return
+	}
+	return smtpExpect(r, "220")
+}
+
+// smtpExpect reads a (multiline) smtp reply and checks the reply code
+func smtpExpect(r *bufio.Reader, code string) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, code) {
+			return fmt.Errorf("%w: %v", ErrStartTLSRejected, strings.TrimSpace(line))
+		}
+		// the last line of a reply has a space after the code
+		if len(line) < 4 || line[3] != '-' {
+			return nil
+		}
+	}
+}
+
+func startTLSIMAP(conn net.Conn) (err error) {
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("%w: %v", ErrStartTLSRejected, strings.TrimSpace(line))
+	}
+	if _, err = io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return
+	}
+	for {
+		if line, err = r.ReadString('\n'); err != nil {
+			return
+		}
+		// skip untagged responses
+		if strings.HasPrefix(line, "*") {
+			continue
+		}
+		if !strings.HasPrefix(line, "a001 OK") {
+			return fmt.Errorf("%w: %v", ErrStartTLSRejected, strings.TrimSpace(line))
+		}
+		return nil
+	}
+}
+
+// ldapStartTLSRequest is BER encoded LDAP ExtendedRequest with StartTLS OID 1.3.6.1.4.1.1466.20037
+var ldapStartTLSRequest = append(
+	[]byte{0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16},
+	[]byte("1.3.6.1.4.1.1466.20037")...,
+)
+
+func startTLSLDAP(conn net.Conn) (err error) {
+	if _, err = conn.Write(ldapStartTLSRequest); err != nil {
+		return
+	}
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+	resultCode, err := ldapExtendedResultCode(buf[:n])
+	if err != nil {
+		return
+	}
+	if resultCode != 0 {
+		return fmt.Errorf("%w: ldap result code %v", ErrStartTLSRejected, resultCode)
+	}
+	return nil
+}
+
+// ldapExtendedResultCode parses the result code of BER encoded LDAP ExtendedResponse
+func ldapExtendedResultCode(data []byte) (int, error) {
+	wrongResponse := errors.New("wrong ldap extended response")
+	// LDAPMessage SEQUENCE
+	data, err := berContent(data, 0x30)
+	if err != nil {
+		return 0, wrongResponse
+	}
+	// skip messageID INTEGER
+	if len(data) < 2 || data[0] != 0x02 || len(data) < 2+int(data[1]) {
+		return 0, wrongResponse
+	}
+	data = data[2+int(data[1]):]
+	// ExtendedResponse [APPLICATION 24]
+	if data, err = berContent(data, 0x78); err != nil {
+		return 0, wrongResponse
+	}
+	// resultCode ENUMERATED
+	if len(data) < 3 || data[0] != 0x0a || data[1] != 0x01 {
+		return 0, wrongResponse
+	}
+	return int(data[2]), nil
+}
+
+// berContent checks the BER tag and returns the element content
+func berContent(data []byte, tag byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != tag {
+		return nil, io.ErrUnexpectedEOF
+	}
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(data) < offset+length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data[offset : offset+length], nil
+}
+
+// postgresSSLRequestCode is the SSLRequest message code from PostgreSQL protocol
+const postgresSSLRequestCode = 80877103
+
+func startTLSPostgres(conn net.Conn) (err error) {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint32(msg[0:4], 8)
+	binary.BigEndian.PutUint32(msg[4:8], postgresSSLRequestCode)
+	if _, err = conn.Write(msg); err != nil {
+		return
+	}
+	resp := make([]byte, 1)
+	if _, err = io.ReadFull(conn, resp); err != nil {
+		return
+	}
+	if resp[0] != 'S' {
+		return fmt.Errorf("%w: postgres response %q", ErrStartTLSRejected, resp[0])
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package tls
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creasty/defaults"
+)
+
+type Probe struct {
+	model.ProbeHandler
+	Config `json:"config"`
+}
+
+type Config struct {
+	Targets            []string // list of host:port
+	ServerName         string   `json:"serverName,omitempty"`         // SNI and verification name, the target host is used by default
+	StartTLS           string   `json:"startTLS,omitempty"`           // smtp | imap | ldap | postgres
+	CAFile             string   `json:"caFile,omitempty"`             // PEM CA bundle, the system pool is used by default
+	InsecureSkipVerify bool     `json:"insecureSkipVerify,omitempty"` // don't validate the chain, check expiry only
+	MinDaysLeft        int      `json:"minDaysLeft" default:"14"`
+	rootCAs            *x509.CertPool
+}
+
+type ResultData struct {
+	Timings    map[string]int      `json:"timings"`
+	DaysLeft   map[string]int      `json:"days_left"`
+	Issuer     map[string]string   `json:"issuer"`
+	Subject    map[string]string   `json:"subject"`
+	SANs       map[string][]string `json:"sans"`
+	TLSVersion map[string]string   `json:"tlsVersion"`
+}
+
+var name = "tls"
+
+var (
+	ErrTimeout = errors.New("timeout")
+	ErrExpires = model.NewReasonError(model.ReasonCertificate, errors.New("certificate expires soon"))
+)
+
+func init() {
+	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
+}
+
+func New(options model.ProbeOptions, config Config) *Probe {
+	p := Probe{}
+	p.ProbeOptions = options
+	p.Name = name
+	_ = defaults.Set(&config)
+	p.Config = config
+	p.ProbeHandler.Config = config
+	p.SetRunner(p.Runner)
+	return &p
+}
+
+//nolint:funlen
+func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
+	var timings model.Timings
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	rd := ResultData{
+		DaysLeft:   make(map[string]int),
+		Issuer:     make(map[string]string),
+		Subject:    make(map[string]string),
+		SANs:       make(map[string][]string),
+		TLSVersion: make(map[string]string),
+	}
+	done := 0
+	for _, target := range c.Targets {
+		wg.Add(1)
+		go func(s string) {
+			t := time.Now()
+			var err error
+			defer func() {
+				dur := time.Since(t)
+				if e := recover(); e != nil {
+					err = fmt.Errorf("panic occurred: %v", e)
+				}
+
+				if err != nil {
+					c.Log("[%v] %v, %vms", s, err, dur.Milliseconds())
+					if c.Expect {
+						c.SetTargetError(s, err)
+					} else {
+						mutex.Lock()
+						done++
+						mutex.Unlock()
+					}
+				} else {
+					if c.Expect {
+						mutex.Lock()
+						done++
+						mutex.Unlock()
+					} else {
+						c.SetTargetError(s, model.ErrUnexpectedSuccess)
+					}
+					c.Log("[%v] OK, %vms", s, dur.Milliseconds())
+				}
+				wg.Done()
+			}()
+
+			state, err := c.handshake(ctx, s)
+			if err != nil {
+				return
+			}
+			timings.Set(s, time.Since(t))
+
+			certs := state.PeerCertificates
+			if len(certs) == 0 {
+				err = errors.New("no peer certificates")
+				return
+			}
+			leaf := certs[0]
+			daysLeft := certsDaysLeft(certs)
+			mutex.Lock()
+			rd.DaysLeft[s] = daysLeft
+			rd.Issuer[s] = leaf.Issuer.String()
+			rd.Subject[s] = leaf.Subject.String()
+			rd.SANs[s] = certSANs(leaf)
+			rd.TLSVersion[s] = tls.VersionName(state.Version)
+			mutex.Unlock()
+
+			if err = c.verify(s, certs); err != nil {
+				return
+			}
+			if daysLeft < c.MinDaysLeft {
+				err = fmt.Errorf("%w: %v days left", ErrExpires, daysLeft)
+			}
+		}(target)
+	}
+	wg.Wait()
+	succ = done == len(c.Targets)
+	rd.Timings = timings.TimingsMs()
+	resultObject = rd
+	return
+}
+
+// handshake connects to the target, negotiates STARTTLS if needed and performs TLS handshake,
+// the chain is verified separately in order to collect certificate data of untrusted peers
+func (c *Probe) handshake(ctx context.Context, target string) (state tls.ConnectionState, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		err = wrapError(err)
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if c.StartTLS != "" {
+		if err = startTLSHandlers[c.StartTLS](conn); err != nil {
+			err = fmt.Errorf("starttls error: %w", wrapError(err))
+			return
+		}
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         c.serverName(target),
+		InsecureSkipVerify: true, //nolint:gosec // the chain is verified by verify method
+	})
+	if err = tlsConn.HandshakeContext(ctx); err != nil {
+		err = fmt.Errorf("tls handshake error: %w", wrapError(err))
+		return
+	}
+	state = tlsConn.ConnectionState()
+	return
+}
+
+func (c *Probe) verify(target string, certs []*x509.Certificate) error {
+	if c.InsecureSkipVerify {
+		return nil
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       c.serverName(target),
+		Roots:         c.rootCAs,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return model.NewReasonError(model.ReasonCertificate, fmt.Errorf("certificate verification error: %w", err))
+	}
+	return nil
+}
+
+func (c *Probe) serverName(target string) string {
+	if c.ServerName != "" {
+		return c.ServerName
+	}
+	host, _, _ := net.SplitHostPort(target)
+	return host
+}
+
+// certsDaysLeft returns number of days until the first certificate in the chain expires
+func certsDaysLeft(certs []*x509.Certificate) int {
+	notAfter := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	return int(math.Floor(time.Until(notAfter).Hours() / 24))
+}
+
+func certSANs(cert *x509.Certificate) (sans []string) {
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return
+}
+
+func wrapError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() ||
+		strings.Contains(err.Error(), "context deadline exceeded") {
+		return ErrTimeout
+	}
+	return err
+}
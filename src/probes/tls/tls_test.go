@@ -0,0 +1,259 @@
+package tls
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTLSServer starts a https server with the httptest certificate (valid for 127.0.0.1 and example.com)
+// and writes its certificate to a PEM file
+func testTLSServer(t *testing.T) (server *httptest.Server, caFile string) {
+	server = httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(server.Close)
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0600); err != nil {
+		t.Fatalf("can't write CA file: %v", err)
+	}
+	return
+}
+
+// testStartTLSServer starts a server that negotiates STARTTLS with the dialog function
+// and then performs TLS handshake with the tlsConfig
+func testStartTLSServer(t *testing.T, tlsConfig *tls.Config, dialog func(conn net.Conn, r *bufio.Reader) bool) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if !dialog(conn, bufio.NewReader(conn)) {
+					return
+				}
+				_ = tls.Server(conn, tlsConfig).Handshake()
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func smtpDialog(conn net.Conn, r *bufio.Reader) bool {
+	_, _ = io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+	if l, _ := r.ReadString('\n'); !strings.HasPrefix(l, "EHLO") {
+		return false
+	}
+	_, _ = io.WriteString(conn, "250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+	if l, _ := r.ReadString('\n'); !strings.HasPrefix(l, "STARTTLS") {
+		return false
+	}
+	_, _ = io.WriteString(conn, "220 ready to start TLS\r\n")
+	return true
+}
+
+func smtpNoTLSDialog(conn net.Conn, r *bufio.Reader) bool {
+	_, _ = io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+	_, _ = r.ReadString('\n')
+	_, _ = io.WriteString(conn, "250 mail.example.com\r\n")
+	_, _ = r.ReadString('\n')
+	_, _ = io.WriteString(conn, "502 command not implemented\r\n")
+	return false
+}
+
+func imapDialog(conn net.Conn, r *bufio.Reader) bool {
+	_, _ = io.WriteString(conn, "* OK IMAP4rev1 ready\r\n")
+	l, _ := r.ReadString('\n')
+	tag := strings.Split(l, " ")[0]
+	_, _ = io.WriteString(conn, tag+" OK begin TLS negotiation now\r\n")
+	return true
+}
+
+func ldapDialog(conn net.Conn, r *bufio.Reader) bool {
+	buf := make([]byte, len(ldapStartTLSRequest))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return false
+	}
+	_, _ = conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+	return true
+}
+
+func postgresDialog(conn net.Conn, r *bufio.Reader) bool {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return false
+	}
+	_, _ = conn.Write([]byte{'S'})
+	return true
+}
+
+func Test_Runner(t *testing.T) {
+	server, caFile := testTLSServer(t)
+	addr := strings.TrimPrefix(server.URL, "https://")
+	_, port, _ := net.SplitHostPort(addr)
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	defOptions := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	type testCase struct {
+		name           string
+		config         Config
+		expectedResult bool
+	}
+
+	cases := []testCase{
+		{
+			"certificate isn't trusted by the system pool",
+			Config{Targets: []string{addr}},
+			false,
+		},
+		{
+			"certificate is trusted by the custom CA",
+			Config{Targets: []string{addr}, CAFile: caFile},
+			true,
+		},
+		{
+			"certificate is valid for SNI override",
+			Config{Targets: []string{"localhost:" + port}, CAFile: caFile, ServerName: "example.com"},
+			true,
+		},
+		{
+			"certificate isn't valid for host name",
+			Config{Targets: []string{"localhost:" + port}, CAFile: caFile},
+			false,
+		},
+		{
+			"verification is disabled",
+			Config{Targets: []string{"localhost:" + port}, InsecureSkipVerify: true},
+			true,
+		},
+		{
+			"certificate expires before threshold",
+			Config{Targets: []string{addr}, CAFile: caFile, MinDaysLeft: 1000000},
+			false,
+		},
+		{
+			"starttls smtp",
+			Config{Targets: []string{testStartTLSServer(t, server.TLS, smtpDialog)}, CAFile: caFile, StartTLS: "smtp"},
+			true,
+		},
+		{
+			"starttls smtp isn't supported by server",
+			Config{Targets: []string{testStartTLSServer(t, server.TLS, smtpNoTLSDialog)}, CAFile: caFile, StartTLS: "smtp"},
+			false,
+		},
+		{
+			"starttls imap",
+			Config{Targets: []string{testStartTLSServer(t, server.TLS, imapDialog)}, CAFile: caFile, StartTLS: "imap"},
+			true,
+		},
+		{
+			"starttls ldap",
+			Config{Targets: []string{testStartTLSServer(t, server.TLS, ldapDialog)}, CAFile: caFile, StartTLS: "ldap"},
+			true,
+		},
+		{
+			"starttls postgres",
+			Config{Targets: []string{testStartTLSServer(t, server.TLS, postgresDialog)}, CAFile: caFile, StartTLS: "postgres"},
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := constructor.NewProbe(defOptions, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if p.Start(ctx) != c.expectedResult {
+				t.Fatalf("probe should return %v", c.expectedResult)
+			}
+		})
+	}
+}
+
+func Test_RunnerResultData(t *testing.T) {
+	server, _ := testTLSServer(t)
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	p := New(
+		model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true},
+		Config{Targets: []string{addr}},
+	)
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "result data"))
+	if p.Start(ctx) {
+		t.Fatal("probe should return false for untrusted certificate")
+	}
+
+	data, ok := p.Result().Data.(ResultData)
+	if !ok {
+		t.Fatalf("probe data should be ResultData, got %T", p.Result().Data)
+	}
+	leaf := server.Certificate()
+	if data.DaysLeft[addr] <= 0 {
+		t.Fatalf("days left should be exported, got %v", data.DaysLeft[addr])
+	}
+	if data.Issuer[addr] != leaf.Issuer.String() {
+		t.Fatalf("issuer should be %v, got %v", leaf.Issuer.String(), data.Issuer[addr])
+	}
+	if data.Subject[addr] != leaf.Subject.String() {
+		t.Fatalf("subject should be %v, got %v", leaf.Subject.String(), data.Subject[addr])
+	}
+	if !strings.Contains(strings.Join(data.SANs[addr], ","), "example.com") {
+		t.Fatalf("SANs should contain example.com, got %v", data.SANs[addr])
+	}
+	if !strings.HasPrefix(data.TLSVersion[addr], "TLS 1.") {
+		t.Fatalf("tls version should be exported, got %v", data.TLSVersion[addr])
+	}
+	if _, ok = data.Timings[addr]; !ok {
+		t.Fatal("timing should be exported")
+	}
+}
+
+func Test_Constructor(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	options := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	if _, err := constructor.NewProbe(options, "example.com:443, example.org:993"); err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+
+	wrongConfigs := []Config{
+		{Targets: []string{"example.com"}},
+		{Targets: []string{"example.com:25"}, StartTLS: "pop3"},
+		{Targets: []string{"example.com:443"}, CAFile: "test/not-exists.pem"},
+		{},
+	}
+	for i, c := range wrongConfigs {
+		if _, err := constructor.NewProbe(options, c); err == nil {
+			t.Errorf("constructor should return an error for config %v", i)
+		}
+	}
+}
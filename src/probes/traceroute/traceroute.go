@@ -30,7 +30,10 @@ type Config struct {
 
 var name = "traceroute"
 
-var ErrTimeout = errors.New("timeout")
+var (
+	ErrTimeout  = errors.New("timeout")
+	ErrMismatch = model.NewReasonError(model.ReasonMismatch, errors.New("expected hops mismatch"))
+)
 
 func init() {
 	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
@@ -71,6 +74,12 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 			}
 			c.Log("[%v] "+r+", %vms", c.Host, c.Duration().Milliseconds())
 		}
+		if !succ {
+			if err == nil {
+				err = ErrMismatch
+			}
+			c.SetTargetError(c.Host, err)
+		}
 	}()
 
 	timer := time.After(c.Timeout)
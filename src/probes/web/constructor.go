@@ -48,7 +48,19 @@ func (c constructor) configuration(conf any) (configuration Config, err error) {
 		return
 	}
 
-	err = configuration.compileRegex()
+	if err = configuration.compileRegex(); err != nil {
+		return
+	}
+	if err = configuration.validateRequests(); err != nil {
+		return
+	}
+	if err = configuration.compileTLS(); err != nil {
+		return
+	}
+	if err = configuration.compileResolve(); err != nil {
+		return
+	}
+	err = configuration.compileJSONAssertions()
 	return
 }
 
@@ -92,3 +104,23 @@ func (c *Config) compileRegex() error {
 	}
 	return nil
 }
+
+func (c *Config) compileJSONAssertions() error {
+	if len(c.JSONAssertions) == 0 {
+		return nil
+	}
+	assertions := make([]JSONAssertion, len(c.JSONAssertions))
+	names := make(map[string]bool, len(assertions))
+	for i, a := range c.JSONAssertions {
+		if err := a.compile(); err != nil {
+			return fmt.Errorf("wrong jsonAssertions: %w", err)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("wrong jsonAssertions: duplicate name %q", a.Name)
+		}
+		names[a.Name] = true
+		assertions[i] = a
+	}
+	c.JSONAssertions = assertions
+	return nil
+}
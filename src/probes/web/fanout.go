@@ -0,0 +1,100 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// backend is the ip the connections to the host are sent to, the host is kept in the Host header and SNI
+type backend struct {
+	host, ip string
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialContext returns the dial function which connects to the backend ip instead of the host,
+// the addresses of other hosts, e.g. after a redirect, are dialed as is
+func (b *backend) dialContext(dial dialFunc) dialFunc {
+	if b == nil {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil && strings.EqualFold(host, b.host) {
+			addr = net.JoinHostPort(b.ip, port)
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// target is the key of the url backend in the result data
+func (b *backend) target(u string) string {
+	if b == nil {
+		return u
+	}
+	return u + "@" + b.ip
+}
+
+// backends returns the backends of the url host: the ips set in resolve or resolved with fanOut,
+// nil if the request is sent as is
+func (c *Config) backends(ctx context.Context, u *url.URL) ([]*backend, error) {
+	host := u.Hostname()
+	ips, ok := c.resolveIPs[strings.ToLower(host)]
+	if !ok {
+		if !c.FanOut {
+			return nil, nil
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP.String())
+		}
+	}
+	backends := make([]*backend, len(ips))
+	for i, ip := range ips {
+		backends[i] = &backend{host: host, ip: ip}
+	}
+	return backends, nil
+}
+
+// compileResolve validates the fan-out options and indexes the resolve ips by lowercase hosts
+func (c *Config) compileResolve() error {
+	if c.Quorum < 0 {
+		return fmt.Errorf("quorum should be greater than or equal to 0")
+	}
+	if c.Quorum > 0 && !c.FanOut && len(c.Resolve) == 0 {
+		return fmt.Errorf("quorum requires fanOut or resolve")
+	}
+	if len(c.Resolve) == 0 {
+		return nil
+	}
+	hosts := make(map[string]bool, len(c.Urls))
+	for _, s := range c.Urls {
+		if u, err := url.Parse(withScheme(s)); err == nil {
+			hosts[strings.ToLower(u.Hostname())] = true
+		}
+	}
+	c.resolveIPs = make(map[string][]string, len(c.Resolve))
+	for host, ips := range c.Resolve {
+		if !hosts[strings.ToLower(host)] {
+			return fmt.Errorf("resolve of unknown host %v", host)
+		}
+		if len(ips) == 0 {
+			return fmt.Errorf("resolve of %v has no ips", host)
+		}
+		if c.Quorum > len(ips) {
+			return fmt.Errorf("quorum %v is greater than the number of ips in resolve of %v", c.Quorum, host)
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("wrong ip %q in resolve of %v", ip, host)
+			}
+		}
+		c.resolveIPs[strings.ToLower(host)] = ips
+	}
+	return nil
+}
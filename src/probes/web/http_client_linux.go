@@ -4,6 +4,7 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"syscall"
@@ -12,28 +13,30 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func newHTTPClient(timeout time.Duration, fwMark int) http.Client {
-	if fwMark == 0 {
+func newHTTPClient(timeout time.Duration, fwMark int, tlsConfig *tls.Config, b *backend) http.Client {
+	if fwMark == 0 && tlsConfig == nil && b == nil {
 		return http.Client{Timeout: timeout}
 	}
 
-	dialer := &net.Dialer{
-		Timeout: timeout,
-		ControlContext: func(_ context.Context, _, _ string, conn syscall.RawConn) error {
-			var sockErr error
-			err := conn.Control(func(fd uintptr) {
-				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, fwMark)
-			})
-			if err != nil {
-				return err
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if fwMark != 0 || b != nil {
+		dialer := &net.Dialer{Timeout: timeout}
+		if fwMark != 0 {
+			dialer.ControlContext = func(_ context.Context, _, _ string, conn syscall.RawConn) error {
+				var sockErr error
+				err := conn.Control(func(fd uintptr) {
+					sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, fwMark)
+				})
+				if err != nil {
+					return err
+				}
+				return sockErr
 			}
-			return sockErr
-		},
+		}
+		transport.DialContext = b.dialContext(dialer.DialContext)
 	}
 
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.DialContext = dialer.DialContext
-
 	return http.Client{
 		Timeout:   timeout,
 		Transport: transport,
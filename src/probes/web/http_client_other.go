@@ -3,10 +3,25 @@
 package web
 
 import (
+	"crypto/tls"
+	"net"
 	"net/http"
 	"time"
 )
 
-func newHTTPClient(timeout time.Duration, _ int) http.Client {
-	return http.Client{Timeout: timeout}
+func newHTTPClient(timeout time.Duration, _ int, tlsConfig *tls.Config, b *backend) http.Client {
+	if tlsConfig == nil && b == nil {
+		return http.Client{Timeout: timeout}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if b != nil {
+		dialer := &net.Dialer{Timeout: timeout}
+		transport.DialContext = b.dialContext(dialer.DialContext)
+	}
+	return http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
 }
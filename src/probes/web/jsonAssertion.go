@@ -0,0 +1,231 @@
+package web
+
+import (
+	"boogieman/src/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONAssertion operators
+const (
+	JSONOpEquals    = "equals"
+	JSONOpNotEquals = "notEquals"
+	JSONOpLt        = "lt"
+	JSONOpGt        = "gt"
+	JSONOpExists    = "exists"
+	JSONOpRegex     = "regex"
+	JSONOpLength    = "length"
+)
+
+// JSONAssertion checks the value selected from the json response body
+type JSONAssertion struct {
+	Name  string `json:"name,omitempty"` // the key of the value in the result data, the path by default
+	Path  string `json:"path"`           // the selector, e.g. $.queue.depth or $.items[0]["status"]
+	Op    string `json:"op"`             // equals | notEquals | lt | gt | exists | regex | length
+	Value any    `json:"value,omitempty"`
+	path  []any  // the object keys and the array indexes
+	value any    // Value as it's decoded from json
+	regex *regexp.Regexp
+}
+
+var errJSONPathNotFound = errors.New("not found")
+
+// compile parses the path and the expected value
+func (a *JSONAssertion) compile() (err error) {
+	if a.path, err = parseJSONPath(a.Path); err != nil {
+		return fmt.Errorf("wrong path %q: %w", a.Path, err)
+	}
+	if a.Name == "" {
+		a.Name = a.Path
+	}
+	if a.Value != nil {
+		data, err := json.Marshal(a.Value)
+		if err != nil {
+			return fmt.Errorf("wrong value of %q: %w", a.Path, err)
+		}
+		_ = json.Unmarshal(data, &a.value)
+	}
+
+	switch a.Op {
+	case JSONOpEquals, JSONOpNotEquals:
+	case JSONOpLt, JSONOpGt, JSONOpLength:
+		if _, ok := a.value.(float64); !ok {
+			return fmt.Errorf("%v of %q requires a number value", a.Op, a.Path)
+		}
+	case JSONOpExists:
+		if _, ok := a.value.(bool); a.value != nil && !ok {
+			return fmt.Errorf("exists of %q requires a boolean value", a.Path)
+		}
+	case JSONOpRegex:
+		s, ok := a.value.(string)
+		if !ok {
+			return fmt.Errorf("regex of %q requires a string value", a.Path)
+		}
+		if a.regex, err = regexp.Compile(s); err != nil {
+			return fmt.Errorf("wrong regex of %q: %w", a.Path, err)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q of %q", a.Op, a.Path)
+	}
+	return nil
+}
+
+// check selects the value from the decoded json body, the value is returned if it's found,
+// the length is returned for the length operator
+func (a *JSONAssertion) check(body any) (value any, err error) {
+	value, found := selectJSONValue(body, a.path)
+	if a.Op == JSONOpExists {
+		expected := a.value == nil || a.value.(bool)
+		if found != expected {
+			if expected {
+				return value, fmt.Errorf("%v is %w", a.Path, errJSONPathNotFound)
+			}
+			return value, fmt.Errorf("%v exists", a.Path)
+		}
+		return value, nil
+	}
+	if !found {
+		return nil, fmt.Errorf("%v is %w", a.Path, errJSONPathNotFound)
+	}
+
+	switch a.Op {
+	case JSONOpEquals, JSONOpNotEquals:
+		if reflect.DeepEqual(value, a.value) != (a.Op == JSONOpEquals) {
+			return value, fmt.Errorf("%v is %v, expected %v %v", a.Path, jsonString(value), a.Op, jsonString(a.value))
+		}
+	case JSONOpLt, JSONOpGt:
+		n, ok := value.(float64)
+		if !ok {
+			return value, fmt.Errorf("%v is %v, not a number", a.Path, jsonString(value))
+		}
+		if (a.Op == JSONOpLt && n >= a.value.(float64)) || (a.Op == JSONOpGt && n <= a.value.(float64)) {
+			return value, fmt.Errorf("%v is %v, expected %v %v", a.Path, jsonString(value), a.Op, jsonString(a.value))
+		}
+	case JSONOpRegex:
+		s, ok := value.(string)
+		if !ok {
+			s = jsonString(value)
+		}
+		if !a.regex.MatchString(s) {
+			return value, fmt.Errorf("%v is %v, doesn't match regex", a.Path, jsonString(value))
+		}
+	case JSONOpLength:
+		var length int
+		switch v := value.(type) {
+		case []any:
+			length = len(v)
+		case map[string]any:
+			length = len(v)
+		case string:
+			length = len(v)
+		default:
+			return value, fmt.Errorf("%v is %v, it has no length", a.Path, jsonString(value))
+		}
+		value = length
+		if float64(length) != a.value.(float64) {
+			return value, fmt.Errorf("%v length is %v, expected %v", a.Path, length, jsonString(a.value))
+		}
+	}
+	return value, nil
+}
+
+// checkJSON decodes the body and checks the assertions, returns the selected values and the assertion results by names
+func (c *Probe) checkJSON(body []byte) (values map[string]any, results map[string]bool, err error) {
+	results = make(map[string]bool, len(c.JSONAssertions))
+	var decoded any
+	if e := json.Unmarshal(body, &decoded); e != nil {
+		for _, a := range c.JSONAssertions {
+			results[a.Name] = false
+		}
+		return nil, results, model.NewReasonError(model.ReasonMismatch, fmt.Errorf("wrong json body: %w", e))
+	}
+	values = make(map[string]any, len(c.JSONAssertions))
+	for i := range c.JSONAssertions {
+		a := &c.JSONAssertions[i]
+		value, e := a.check(decoded)
+		if value != nil {
+			values[a.Name] = value
+		}
+		results[a.Name] = e == nil
+		if e != nil && err == nil {
+			err = model.NewReasonError(model.ReasonMismatch, e)
+		}
+	}
+	return
+}
+
+// parseJSONPath parses the selector of object keys and array indexes: $.a.b, $.a[0], $["a.b"], a.b
+func parseJSONPath(path string) (keys []any, err error) {
+	p := strings.TrimPrefix(path, "$")
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, errors.New("empty key")
+			}
+			keys = append(keys, p[:end])
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, errors.New("unclosed bracket")
+			}
+			item := p[1:end]
+			p = p[end+1:]
+			if len(item) >= 2 && (item[0] == '"' || item[0] == '\'') && item[len(item)-1] == item[0] {
+				keys = append(keys, item[1:len(item)-1])
+				continue
+			}
+			index, e := strconv.Atoi(item)
+			if e != nil || index < 0 {
+				return nil, fmt.Errorf("wrong index %q", item)
+			}
+			keys = append(keys, index)
+		default:
+			if p != path {
+				return nil, fmt.Errorf("unexpected %q", p)
+			}
+			// a path without the leading $.
+			p = "." + p
+		}
+	}
+	return
+}
+
+// selectJSONValue returns the value of the decoded json by the path
+func selectJSONValue(v any, path []any) (any, bool) {
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[k]; !ok {
+				return nil, false
+			}
+		case int:
+			a, ok := v.([]any)
+			if !ok || k >= len(a) {
+				return nil, false
+			}
+			v = a[k]
+		}
+	}
+	return v, true
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
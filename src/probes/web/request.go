@@ -0,0 +1,191 @@
+package web
+
+import (
+	"boogieman/src/model"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const redacted = "*****"
+
+// Request is the options of the http request, set for all urls by Config or for the url by Config.Overrides
+type Request struct {
+	Method      string            `json:"method,omitempty"` // GET by default, POST if the body is set
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	BodyFile    string            `json:"bodyFile,omitempty"` // the file is read every run
+	BasicAuth   *BasicAuth        `json:"basicAuth,omitempty"`
+	BearerToken string            `json:"bearerToken,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// sensitiveHeaders are the header name parts of the values redacted in the probe result configuration
+var sensitiveHeaders = []string{"authorization", "cookie", "token", "secret", "password", "api-key", "apikey"}
+
+// merge returns the request with the options of the override set
+func (r Request) merge(override Request) Request {
+	if override.Method != "" {
+		r.Method = override.Method
+	}
+	if len(override.Headers) > 0 {
+		headers := make(map[string]string, len(r.Headers)+len(override.Headers))
+		for k, v := range r.Headers {
+			headers[k] = v
+		}
+		for k, v := range override.Headers {
+			headers[k] = v
+		}
+		r.Headers = headers
+	}
+	if override.Body != "" || override.BodyFile != "" {
+		r.Body, r.BodyFile = override.Body, override.BodyFile
+	}
+	if override.BasicAuth != nil || override.BearerToken != "" {
+		r.BasicAuth, r.BearerToken = override.BasicAuth, override.BearerToken
+	}
+	return r
+}
+
+func (r Request) validate() error {
+	if strings.ContainsAny(r.Method, " \t\r\n") {
+		return fmt.Errorf("wrong method %q", r.Method)
+	}
+	if r.Body != "" && r.BodyFile != "" {
+		return fmt.Errorf("body cannot be used with bodyFile")
+	}
+	if r.BasicAuth != nil && r.BearerToken != "" {
+		return fmt.Errorf("basicAuth cannot be used with bearerToken")
+	}
+	return nil
+}
+
+// redacted returns the request with the secrets replaced
+func (r Request) redacted() Request {
+	if len(r.Headers) > 0 {
+		headers := make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			if isSensitiveHeader(k) {
+				v = redacted
+			}
+			headers[k] = v
+		}
+		r.Headers = headers
+	}
+	if r.BasicAuth != nil {
+		r.BasicAuth = &BasicAuth{Username: r.BasicAuth.Username, Password: redacted}
+	}
+	if r.BearerToken != "" {
+		r.BearerToken = redacted
+	}
+	return r
+}
+
+// newHTTPRequest creates the http request to the url with the options
+func (r Request) newHTTPRequest(ctx context.Context, u string) (*http.Request, error) {
+	var body io.Reader
+	switch {
+	case r.BodyFile != "":
+		data, err := os.ReadFile(r.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read body file: %w", err)
+		}
+		body = bytes.NewReader(data)
+	case r.Body != "":
+		body = strings.NewReader(r.Body)
+	}
+
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, model.NewReasonError(model.ReasonConfig, err)
+	}
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	if r.BasicAuth != nil {
+		req.SetBasicAuth(r.BasicAuth.Username, r.BasicAuth.Password)
+	}
+	if r.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.BearerToken)
+	}
+	return req, nil
+}
+
+// request returns the request options for the url as it's set in urls
+func (c Config) request(u string) Request {
+	r := Request{
+		Method: c.Method, Headers: c.Headers, Body: c.Body, BodyFile: c.BodyFile,
+		BasicAuth: c.BasicAuth, BearerToken: c.BearerToken,
+	}
+	if override, ok := c.Overrides[u]; ok {
+		return r.merge(override)
+	}
+	return r
+}
+
+// validateRequests checks the request options and the overrides
+func (c Config) validateRequests() error {
+	if err := c.request("").validate(); err != nil {
+		return err
+	}
+	for u := range c.Overrides {
+		found := false
+		for _, s := range c.Urls {
+			if s == u {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("override of url %q that isn't in urls", u)
+		}
+		if err := c.request(u).validate(); err != nil {
+			return fmt.Errorf("override of url %q: %w", u, err)
+		}
+	}
+	return nil
+}
+
+// redacted returns the configuration with the secrets replaced to be shown in the probe result
+func (c Config) redacted() Config {
+	r := c.request("").redacted()
+	c.Headers, c.BasicAuth, c.BearerToken = r.Headers, r.BasicAuth, r.BearerToken
+	if len(c.Overrides) > 0 {
+		overrides := make(map[string]Request, len(c.Overrides))
+		for u, override := range c.Overrides {
+			overrides[u] = override.redacted()
+		}
+		c.Overrides = overrides
+	}
+	return c
+}
+
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveHeaders {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
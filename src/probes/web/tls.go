@@ -0,0 +1,97 @@
+package web
+
+import (
+	"boogieman/src/model"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// DefaultMaxRedirects is the number of redirects followed by default and with followRedirects: true
+const DefaultMaxRedirects = 10
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Redirects is the max number of redirects to follow, it's set as a boolean or a number
+type Redirects int
+
+func (r *Redirects) UnmarshalJSON(data []byte) error {
+	var follow bool
+	if err := json.Unmarshal(data, &follow); err == nil {
+		*r = 0
+		if follow {
+			*r = DefaultMaxRedirects
+		}
+		return nil
+	}
+	var max int
+	if err := json.Unmarshal(data, &max); err != nil || max < 0 {
+		return errors.New("followRedirects should be a boolean or a non-negative number")
+	}
+	*r = Redirects(max)
+	return nil
+}
+
+// compileTLS creates the tls configuration of the client if any tls option is set
+func (c *Config) compileTLS() error {
+	if c.CAFile == "" && c.CertFile == "" && c.KeyFile == "" && !c.InsecureSkipVerify &&
+		c.ServerName == "" && c.MinTLSVersion == "" {
+		return nil
+	}
+	//nolint:gosec // insecureSkipVerify is set by the user explicitly
+	config := &tls.Config{ServerName: c.ServerName, InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return fmt.Errorf("can't read CA bundle %v: %w", c.CAFile, err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in CA bundle %v", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return fmt.Errorf("certFile and keyFile should be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return fmt.Errorf("can't load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if c.MinTLSVersion != "" {
+		version, ok := tlsVersions[c.MinTLSVersion]
+		if !ok {
+			return fmt.Errorf("wrong minTLSVersion %q, it should be 1.0, 1.1, 1.2 or 1.3", c.MinTLSVersion)
+		}
+		config.MinVersion = version
+	}
+	c.tlsConfig = config
+	return nil
+}
+
+// checkRedirect stops following redirects after the configured number or DefaultMaxRedirects,
+// the last redirect response is returned if redirects aren't followed
+func (c *Config) checkRedirect(_ *http.Request, via []*http.Request) error {
+	max := Redirects(DefaultMaxRedirects)
+	if c.FollowRedirects != nil {
+		max = *c.FollowRedirects
+	}
+	if max == 0 {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > int(max) {
+		return model.NewReasonError(model.ReasonRedirect, fmt.Errorf("stopped after %d redirects", max))
+	}
+	return nil
+}
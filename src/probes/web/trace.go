@@ -0,0 +1,107 @@
+package web
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// request phases
+const (
+	PhaseDNS      = "dns"      // the host name lookup
+	PhaseConnect  = "connect"  // the tcp connection
+	PhaseTLS      = "tls"      // the tls handshake
+	PhaseTTFB     = "ttfb"     // from the request written to the first response byte
+	PhaseTransfer = "transfer" // from the first response byte to the end of the body
+)
+
+// phaseTracer keeps the times of the request phases, the phases of a reused connection aren't traced,
+// only the last request is traced if redirects are followed
+type phaseTracer struct {
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+	bodyRead                  time.Time
+	sync.Mutex
+}
+
+// withTrace returns the request with the tracer in the context
+func (p *phaseTracer) withTrace(req *http.Request) *http.Request {
+	now := func(t *time.Time) {
+		p.Lock()
+		*t = time.Now()
+		p.Unlock()
+	}
+	trace := &httptrace.ClientTrace{
+		// every request, including the redirected ones, starts with getting a connection
+		GetConn:  func(string) { p.reset() },
+		DNSStart: func(httptrace.DNSStartInfo) { now(&p.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { now(&p.dnsDone) },
+		ConnectStart: func(string, string) {
+			p.Lock()
+			// the first one of the parallel dual-stack connections
+			if p.connectStart.IsZero() {
+				p.connectStart = time.Now()
+			}
+			p.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				now(&p.connectDone)
+			}
+		},
+		TLSHandshakeStart:    func() { now(&p.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { now(&p.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { now(&p.wroteRequest) },
+		GotFirstResponseByte: func() { now(&p.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// reset clears the times of the previous request
+func (p *phaseTracer) reset() {
+	p.Lock()
+	defer p.Unlock()
+	p.dnsStart, p.dnsDone = time.Time{}, time.Time{}
+	p.connectStart, p.connectDone = time.Time{}, time.Time{}
+	p.tlsStart, p.tlsDone = time.Time{}, time.Time{}
+	p.wroteRequest, p.firstByte = time.Time{}, time.Time{}
+	p.bodyRead = time.Time{}
+}
+
+// done marks the end of the body reading
+func (p *phaseTracer) done() {
+	p.Lock()
+	p.bodyRead = time.Now()
+	p.Unlock()
+}
+
+// phases returns the durations of the completed phases in milliseconds
+func (p *phaseTracer) phases() map[string]float64 {
+	p.Lock()
+	defer p.Unlock()
+	phases := make(map[string]float64)
+	for _, phase := range []struct {
+		name       string
+		start, end time.Time
+	}{
+		{PhaseDNS, p.dnsStart, p.dnsDone},
+		{PhaseConnect, p.connectStart, p.connectDone},
+		{PhaseTLS, p.tlsStart, p.tlsDone},
+		{PhaseTTFB, p.wroteRequest, p.firstByte},
+		{PhaseTransfer, p.firstByte, p.bodyRead},
+	} {
+		if !phase.start.IsZero() && !phase.end.IsZero() {
+			phases[phase.name] = durationMs(phase.end.Sub(phase.start))
+		}
+	}
+	return phases
+}
+
+// durationMs returns the duration in milliseconds with microseconds precision
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -4,6 +4,7 @@ import (
 	"boogieman/src/model"
 	"boogieman/src/probefactory"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -23,29 +24,58 @@ type Probe struct {
 type Config struct {
 	HTTPStatus         int
 	Urls               []string
-	FWMark             int    `json:"fwMark,omitempty"`
-	Regex              string `json:"regex,omitempty"`
-	RegexInvert        bool   `json:"regexInvert,omitempty"`
-	RegexRequired      *bool  `json:"regexRequired,omitempty"`
-	RegexCaptureGroup  int    `json:"regexCaptureGroup,omitempty"`
-	CaptureRegex       string `json:"captureRegex,omitempty"`
-	CaptureRegexInvert bool   `json:"captureRegexInvert,omitempty"`
+	FWMark             int                 `json:"fwMark,omitempty"`
+	Regex              string              `json:"regex,omitempty"`
+	RegexInvert        bool                `json:"regexInvert,omitempty"`
+	RegexRequired      *bool               `json:"regexRequired,omitempty"`
+	RegexCaptureGroup  int                 `json:"regexCaptureGroup,omitempty"`
+	CaptureRegex       string              `json:"captureRegex,omitempty"`
+	CaptureRegexInvert bool                `json:"captureRegexInvert,omitempty"`
+	Method             string              `json:"method,omitempty"` // GET by default, POST if the body is set
+	Headers            map[string]string   `json:"headers,omitempty"`
+	Body               string              `json:"body,omitempty"`
+	BodyFile           string              `json:"bodyFile,omitempty"` // the file is read every run
+	BasicAuth          *BasicAuth          `json:"basicAuth,omitempty"`
+	BearerToken        string              `json:"bearerToken,omitempty"`
+	Overrides          map[string]Request  `json:"overrides,omitempty"` // request options by urls as they're set in urls
+	JSONAssertions     []JSONAssertion     `json:"jsonAssertions,omitempty"`
+	CAFile             string              `json:"caFile,omitempty"` // the CA bundle to verify the server certificate
+	CertFile           string              `json:"certFile,omitempty"`
+	KeyFile            string              `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool                `json:"insecureSkipVerify,omitempty"`
+	ServerName         string              `json:"serverName,omitempty"`    // the SNI and the name to verify the certificate
+	MinTLSVersion      string              `json:"minTLSVersion,omitempty"` // 1.0 | 1.1 | 1.2 | 1.3
+	FollowRedirects    *Redirects          `json:"followRedirects,omitempty"`
+	ExpectedURL        string              `json:"expectedUrl,omitempty"`   // the final url after redirects
+	TraceTransfer      bool                `json:"traceTransfer,omitempty"` // read the unchecked body to trace the transfer
+	FanOut             bool                `json:"fanOut,omitempty"`        // send the request to every resolved ip of the url host
+	Resolve            map[string][]string `json:"resolve,omitempty"`       // the ips of the hosts, like curl --resolve
+	Quorum             int                 `json:"quorum,omitempty"`        // the min number of succeeded ips of the url, all by default
 	regexp             *regexp.Regexp
 	captureRegexp      *regexp.Regexp
+	tlsConfig          *tls.Config
+	resolveIPs         map[string][]string
 }
 
 type ResultData struct {
-	Timings        map[string]int    `json:"timings"`
-	HTTPStatus     map[string]int    `json:"httpStatus"`
-	Regex          map[string]bool   `json:"regex,omitempty"`
-	Captures       map[string]string `json:"captures,omitempty"`
-	CaptureMatches map[string]bool   `json:"captureMatches,omitempty"`
+	Timings        map[string]int                `json:"timings"`
+	HTTPStatus     map[string]int                `json:"httpStatus"`
+	Regex          map[string]bool               `json:"regex,omitempty"`
+	Captures       map[string]string             `json:"captures,omitempty"`
+	CaptureMatches map[string]bool               `json:"captureMatches,omitempty"`
+	JSON           map[string]map[string]any     `json:"json,omitempty"`                     // selected values by urls and assertion names
+	JSONAssertions map[string]map[string]bool    `json:"jsonAssertions,omitempty"`           // assertion results by urls and assertion names
+	Phases         map[string]map[string]float64 `json:"phases,omitempty" metricKey:"phase"` // request phase durations in ms by urls
+	FinalURLs      map[string]string             `json:"finalUrls,omitempty"`                // the urls after redirects
 }
 
 var name = "web"
 var ErrTimeout = errors.New("timeout")
 var DefaultHttpScheme = "https"
 
+// maxDrainSize is the max size of the unchecked body read to trace the transfer
+const maxDrainSize = 1 << 20
+
 func init() {
 	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
 }
@@ -55,7 +85,7 @@ func New(options model.ProbeOptions, config Config) *Probe {
 	p.ProbeOptions = options
 	p.ProbeHandler.Name = name
 	p.Config = config
-	p.ProbeHandler.Config = config
+	p.ProbeHandler.Config = config.redacted()
 	p.SetRunner(p.Runner)
 	return &p
 }
@@ -69,6 +99,10 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 	captureMatches := make(map[string]bool)
 	httpStatus := make(map[string]int)
 	regex := make(map[string]bool)
+	jsonValues := make(map[string]map[string]any)
+	jsonAssertions := make(map[string]map[string]bool)
+	phases := make(map[string]map[string]float64)
+	finalURLs := make(map[string]string)
 	setFailedData := func(s string) {
 		if c.regexp != nil {
 			regex[s] = false
@@ -79,84 +113,177 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 		if c.captureRegexp != nil {
 			captureMatches[s] = false
 		}
+		if len(c.JSONAssertions) > 0 {
+			jsonAssertions[s] = make(map[string]bool, len(c.JSONAssertions))
+			for _, a := range c.JSONAssertions {
+				jsonAssertions[s][a.Name] = false
+			}
+		}
+	}
+	// the number of the url targets, the required and the ones with the expected result by urls
+	targets := make(map[string]int, len(c.Urls))
+	required := make(map[string]int, len(c.Urls))
+	done := make(map[string]int, len(c.Urls))
+	setDone := func(s, target string, err error) {
+		mutex.Lock()
+		if (err == nil) == c.Expect {
+			done[s]++
+		}
+		mutex.Unlock()
+		if err != nil && c.Expect {
+			c.SetTargetError(target, err)
+		} else if err == nil && !c.Expect {
+			c.SetTargetError(target, model.ErrUnexpectedSuccess)
+		}
 	}
-	done := 0
 	for _, s := range c.Urls {
-		if u, e := url.Parse(s); e != nil {
+		request := c.request(s)
+		u, e := url.Parse(withScheme(s))
+		if e != nil {
 			c.Log("wrong url %v", s)
+			c.SetTargetError(s, model.NewReasonError(model.ReasonConfig, e))
 			return false, nil
-		} else if u.Scheme == "" {
-			s = DefaultHttpScheme + "://" + s
-		}
-
-		wg.Add(1)
-		go func(s string) {
-			t := time.Now()
-			var (
-				dur time.Duration
-				err error
-				r   *http.Response
-			)
-			defer func() {
-				dur = time.Since(t)
-				if err != nil {
-					c.Log("[%v] %v, %vms", s, err, dur.Milliseconds())
-					if !c.Expect {
+		}
+		s = withScheme(s)
+
+		backends, e := c.backends(ctx, u)
+		if e != nil {
+			c.Log("[%v] can't resolve %v: %v", s, u.Hostname(), e)
+			targets[s], required[s] = 1, 1
+			mutex.Lock()
+			setFailedData(s)
+			mutex.Unlock()
+			setDone(s, s, fmt.Errorf("can't resolve %v: %w", u.Hostname(), e))
+			continue
+		}
+		targets[s], required[s] = len(backends), len(backends)
+		if c.Quorum > 0 && backends != nil {
+			// the quorum is applied to the fanned-out urls only
+			required[s] = c.Quorum
+		}
+		if backends == nil {
+			// the request is sent as is
+			backends = []*backend{nil}
+			targets[s], required[s] = 1, 1
+		}
+
+		for _, b := range backends {
+			wg.Add(1)
+			go func(s string, b *backend) {
+				target := b.target(s)
+				t := time.Now()
+				var (
+					dur    time.Duration
+					err    error
+					r      *http.Response
+					tracer phaseTracer
+					client = c.httpClient(b)
+				)
+				defer func() {
+					dur = time.Since(t)
+					if p := tracer.phases(); len(p) > 0 {
 						mutex.Lock()
-						done++
+						phases[target] = p
 						mutex.Unlock()
 					}
-				} else {
-					if c.Expect {
-						mutex.Lock()
-						done++
-						mutex.Unlock()
+					if err != nil {
+						c.Log("[%v] %v, %vms", target, err, dur.Milliseconds())
+					} else {
+						c.Log("[%v] OK, %vms", target, dur.Milliseconds())
+					}
+					setDone(s, target, err)
+					if r != nil {
+						_ = r.Body.Close()
+					}
+					closeIdle(client)
+					wg.Done()
+				}()
+
+				req, err := request.newHTTPRequest(ctx, s)
+				if err != nil {
+					mutex.Lock()
+					setFailedData(target)
+					mutex.Unlock()
+					return
+				}
+				r, err = client.Do(tracer.withTrace(req))
+				if err != nil {
+					var certErr *tls.CertificateVerificationError
+					switch {
+					case strings.Contains(err.Error(), "context deadline exceeded"):
+						err = ErrTimeout
+					case errors.As(err, &certErr):
+						err = model.NewReasonError(model.ReasonCertificate, fmt.Errorf("http error %w", err))
+					default:
+						err = fmt.Errorf("http error %w", err)
 					}
-					c.Log("[%v] OK, %vms", s, dur.Milliseconds())
+					mutex.Lock()
+					setFailedData(target)
+					mutex.Unlock()
+					return
 				}
-				if r != nil {
-					_ = r.Body.Close()
+				timings.Set(target, time.Since(t))
+
+				body, read, bodyErr := c.readBody(r)
+				if read {
+					tracer.done()
 				}
-				wg.Done()
-			}()
-
-			client := c.httpClient()
-			r, err = client.Get(s)
-			if err != nil {
-				if strings.Contains(err.Error(), "context deadline exceeded") {
-					err = ErrTimeout
-				} else {
-					err = fmt.Errorf("http error %w", err)
+				matched, capture, captureMatched, regexErr := c.checkBody(body)
+				var (
+					values  map[string]any
+					results map[string]bool
+					jsonErr error
+				)
+				if len(c.JSONAssertions) > 0 {
+					values, results, jsonErr = c.checkJSON(body)
 				}
+				finalURL := r.Request.URL.String()
 				mutex.Lock()
-				setFailedData(s)
+				httpStatus[target] = r.StatusCode
+				if c.reportFinalURL() {
+					finalURLs[target] = finalURL
+				}
+				if c.regexp != nil {
+					regex[target] = matched
+				}
+				if c.RegexCaptureGroup > 0 {
+					captures[target] = capture
+				}
+				if captureMatched != nil {
+					captureMatches[target] = *captureMatched
+				}
+				if len(c.JSONAssertions) > 0 {
+					jsonValues[target] = values
+					jsonAssertions[target] = results
+				}
 				mutex.Unlock()
-				return
-			}
-			timings.Set(s, time.Since(t))
-
-			matched, capture, captureMatched, bodyErr := c.checkBody(r)
-			mutex.Lock()
-			httpStatus[s] = r.StatusCode
-			if c.regexp != nil {
-				regex[s] = matched
-			}
-			if c.RegexCaptureGroup > 0 {
-				captures[s] = capture
-			}
-			if captureMatched != nil {
-				captureMatches[s] = *captureMatched
-			}
-			mutex.Unlock()
-			if c.HTTPStatus != 0 && r.StatusCode != c.HTTPStatus {
-				err = fmt.Errorf("wrong response %v", r.StatusCode)
-				return
-			}
-			err = bodyErr
-		}(s)
+				if c.HTTPStatus != 0 && r.StatusCode != c.HTTPStatus {
+					err = model.NewReasonError(model.ReasonWrongStatus, fmt.Errorf("wrong response %v", r.StatusCode))
+					return
+				}
+				switch {
+				case c.ExpectedURL != "" && finalURL != c.ExpectedURL:
+					err = model.NewReasonError(model.ReasonRedirect, fmt.Errorf("final url %v, expected %v", finalURL, c.ExpectedURL))
+				case bodyErr != nil:
+					err = bodyErr
+				case regexErr != nil:
+					err = regexErr
+				default:
+					err = jsonErr
+				}
+			}(s, b)
+		}
 	}
 	wg.Wait()
-	succ = done == len(c.Urls)
+	succ = true
+	for s, n := range targets {
+		if done[s] < required[s] {
+			if n > 1 || required[s] > 1 {
+				c.Log("[%v] %v of %v backends succeeded, %v required", s, done[s], n, required[s])
+			}
+			succ = false
+		}
+	}
 
 	rd := ResultData{
 		Timings:    timings.TimingsMs(),
@@ -171,23 +298,72 @@ func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
 	if c.captureRegexp != nil {
 		rd.CaptureMatches = captureMatches
 	}
+	if len(c.JSONAssertions) > 0 {
+		rd.JSON = jsonValues
+		rd.JSONAssertions = jsonAssertions
+	}
+	if len(phases) > 0 {
+		rd.Phases = phases
+	}
+	if c.reportFinalURL() {
+		rd.FinalURLs = finalURLs
+	}
 	resultObject = rd
 
 	return
 }
 
-func (c *Probe) httpClient() http.Client {
-	return newHTTPClient(c.Timeout, c.FWMark)
+func (c *Probe) httpClient(b *backend) http.Client {
+	client := newHTTPClient(c.Timeout, c.FWMark, c.tlsConfig, b)
+	client.CheckRedirect = c.checkRedirect
+	return client
 }
 
-func (c *Probe) checkBody(r *http.Response) (matched bool, capture string, captureMatched *bool, err error) {
-	if c.regexp == nil {
-		return
+// closeIdle closes the connections of the transport created for the request, the default one is shared
+func closeIdle(client http.Client) {
+	if client.Transport != nil {
+		client.CloseIdleConnections()
+	}
+}
+
+// withScheme adds the default scheme to the url without one
+func withScheme(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Scheme == "" {
+		return DefaultHttpScheme + "://" + s
 	}
+	return s
+}
+
+// reportFinalURL is true if the urls after redirects are in the result data
+func (c *Probe) reportFinalURL() bool {
+	return c.FollowRedirects != nil || c.ExpectedURL != ""
+}
 
-	body, err := io.ReadAll(r.Body)
+// readBody reads the response body, it's returned if it's checked by the regex or the json assertions,
+// otherwise it's drained up to maxDrainSize with traceTransfer only, read is true if the whole body is read
+func (c *Probe) readBody(r *http.Response) (body []byte, read bool, err error) {
+	if c.regexp == nil && len(c.JSONAssertions) == 0 {
+		if !c.TraceTransfer {
+			return nil, false, nil
+		}
+		n, err := io.Copy(io.Discard, io.LimitReader(r.Body, maxDrainSize+1))
+		switch {
+		case err != nil:
+			c.Log("[%v] transfer isn't traced: %v", r.Request.URL, err)
+		case n > maxDrainSize:
+			c.Log("[%v] transfer isn't traced: the body is larger than %v bytes", r.Request.URL, maxDrainSize)
+		}
+		return nil, err == nil && n <= maxDrainSize, nil
+	}
+	body, err = io.ReadAll(r.Body)
 	if err != nil {
-		err = fmt.Errorf("can't read response body: %w", err)
+		return body, false, fmt.Errorf("can't read response body: %w", err)
+	}
+	return body, true, nil
+}
+
+func (c *Probe) checkBody(body []byte) (matched bool, capture string, captureMatched *bool, err error) {
+	if c.regexp == nil {
 		return
 	}
 
@@ -201,10 +377,10 @@ func (c *Probe) checkBody(r *http.Response) (matched bool, capture string, captu
 		captureMatched = &cm
 		if cm == c.CaptureRegexInvert {
 			if c.CaptureRegexInvert {
-				err = fmt.Errorf("capture matches forbidden regex")
+				err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("capture matches forbidden regex"))
 				return
 			}
-			err = fmt.Errorf("capture doesn't match regex")
+			err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("capture doesn't match regex"))
 			return
 		}
 	}
@@ -213,10 +389,10 @@ func (c *Probe) checkBody(r *http.Response) (matched bool, capture string, captu
 	}
 	if matched == c.RegexInvert {
 		if c.RegexInvert {
-			err = fmt.Errorf("body matches forbidden regex")
+			err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("body matches forbidden regex"))
 			return
 		}
-		err = fmt.Errorf("body doesn't match regex")
+		err = model.NewReasonError(model.ReasonRegexMismatch, errors.New("body doesn't match regex"))
 		return
 	}
 	return
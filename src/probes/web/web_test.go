@@ -4,10 +4,27 @@ import (
 	"boogieman/src/model"
 	"boogieman/src/probefactory"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -290,6 +307,9 @@ func Test_RunnerReturnsDataOnFailedCondition(t *testing.T) {
 	if _, ok = data.Timings[server.URL]; !ok {
 		t.Fatal("timing should be exported")
 	}
+	if reason := result.Errors[server.URL].Reason; reason != model.ReasonWrongStatus {
+		t.Fatalf("error reason should be %q, got %q", model.ReasonWrongStatus, reason)
+	}
 }
 
 func Test_RunnerReturnsDataOnConnectionFailure(t *testing.T) {
@@ -350,6 +370,9 @@ func Test_RunnerReturnsDataOnConnectionFailure(t *testing.T) {
 	if _, ok = data.Timings[url]; ok {
 		t.Fatal("timing should not be exported when endpoint does not respond")
 	}
+	if reason := result.Errors[url].Reason; reason != model.ReasonConnRefused {
+		t.Fatalf("error reason should be %q, got %q", model.ReasonConnRefused, reason)
+	}
 }
 
 func Test_RunnerRegexDoesNotValidateWhenDisabled(t *testing.T) {
@@ -597,3 +620,870 @@ func Test_ConstructorWrongRegexCaptureGroup(t *testing.T) {
 		t.Fatal("constructor should return an error for invalid regexCaptureGroup")
 	}
 }
+
+// echoServer responds with the request method, the headers and the body, one per line
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("method: " + r.Method + "\n"))
+		_, _ = w.Write([]byte("host: " + r.Host + "\n"))
+		for _, k := range []string{"Authorization", "Content-Type", "X-Env"} {
+			_, _ = w.Write([]byte(strings.ToLower(k) + ": " + r.Header.Get(k) + "\n"))
+		}
+		_, _ = w.Write([]byte("body: " + string(body) + "\n"))
+	}))
+}
+
+//nolint:funlen
+func Test_RunnerRequest(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"from":"file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	cases := []struct {
+		name   string
+		config Config
+		regex  string
+	}{
+		{
+			name:   "get by default",
+			config: Config{},
+			regex:  `method: GET\n(.*\n)*body: \n`,
+		},
+		{
+			name:   "method, headers and body",
+			config: Config{Method: "put", Headers: map[string]string{"Content-Type": "application/json", "X-Env": "test"}, Body: `{"a":1}`},
+			regex:  `method: PUT\n(.*\n)*content-type: application/json\nx-env: test\nbody: \{"a":1\}\n`,
+		},
+		{
+			name:   "post with the body file",
+			config: Config{BodyFile: bodyFile},
+			regex:  `method: POST\n(.*\n)*body: \{"from":"file"\}\n`,
+		},
+		{
+			name:   "basic auth",
+			config: Config{BasicAuth: &BasicAuth{Username: "user", Password: "secret"}},
+			regex:  `authorization: Basic dXNlcjpzZWNyZXQ=\n`,
+		},
+		{
+			name:   "bearer token",
+			config: Config{BearerToken: "secret"},
+			regex:  `authorization: Bearer secret\n`,
+		},
+		{
+			name:   "host header",
+			config: Config{Headers: map[string]string{"Host": "service.local"}},
+			regex:  `host: service.local\n`,
+		},
+		{
+			name: "url override",
+			config: Config{
+				Headers:     map[string]string{"X-Env": "test"},
+				BearerToken: "common",
+				Overrides: map[string]Request{
+					"URL": {Method: http.MethodDelete, Headers: map[string]string{"Content-Type": "text/plain"}, BearerToken: "url"},
+				},
+			},
+			regex: `method: DELETE\n(.*\n)*authorization: Bearer url\ncontent-type: text/plain\nx-env: test\n`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.Urls = []string{server.URL}
+			if override, ok := c.config.Overrides["URL"]; ok {
+				c.config.Overrides = map[string]Request{server.URL: override}
+			}
+			c.config.HTTPStatus = http.StatusOK
+			c.config.Regex = c.regex
+			p, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if !p.Start(ctx) {
+				t.Fatalf("probe should return true, errors %v", p.Result().Errors)
+			}
+		})
+	}
+}
+
+func Test_RunnerRequestBodyFileError(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	p := New(
+		model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true},
+		Config{Urls: []string{server.URL}, BodyFile: filepath.Join(t.TempDir(), "missing.json")},
+	)
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "body file error"))
+	if p.Start(ctx) {
+		t.Fatal("probe should return false because the body file doesn't exist")
+	}
+	if _, ok := p.Result().Errors[server.URL]; !ok {
+		t.Fatal("the error of the url should be in the result")
+	}
+}
+
+func Test_ConstructorWrongRequest(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	cases := map[string]Config{
+		"body with body file":     {Body: "a", BodyFile: "a.json"},
+		"basic auth with token":   {BasicAuth: &BasicAuth{Username: "a"}, BearerToken: "b"},
+		"wrong method":            {Method: "GET POST"},
+		"override of unknown url": {Overrides: map[string]Request{"https://example.org/": {Method: http.MethodPost}}},
+		"wrong override":          {Body: "a", Overrides: map[string]Request{"https://example.com/": {BearerToken: "b", BasicAuth: &BasicAuth{}}}},
+	}
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			config.Urls = []string{"https://example.com/"}
+			if _, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Second, Expect: true}, config); err == nil {
+				t.Fatal("constructor should return an error")
+			}
+		})
+	}
+}
+
+func Test_ConfigurationRedacted(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	p, err := constructor.NewProbe(
+		model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true},
+		Config{
+			Urls:      []string{server.URL},
+			Headers:   map[string]string{"X-Api-Key": "key", "Cookie": "session=1", "X-Env": "test"},
+			BasicAuth: &BasicAuth{Username: "user", Password: "password"},
+			Overrides: map[string]Request{server.URL: {BearerToken: "token"}},
+			Regex:     `authorization: Bearer token\n`,
+		},
+	)
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "redacted"))
+	if !p.Start(ctx) {
+		t.Fatal("probe should send the secrets")
+	}
+
+	data, err := json.Marshal(p.Result().Configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{`:"key"`, "session=1", `:"password"`, `:"token"`} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("configuration %s shouldn't contain %v", data, secret)
+		}
+	}
+	for _, value := range []string{`"X-Env":"test"`, `"username":"user"`} {
+		if !strings.Contains(string(data), value) {
+			t.Errorf("configuration %s should contain %v", data, value)
+		}
+	}
+}
+
+func testWritePEM(t *testing.T, file, blockType string, data []byte) string {
+	file = filepath.Join(t.TempDir(), file)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600); err != nil {
+		t.Fatalf("can't write %v: %v", file, err)
+	}
+	return file
+}
+
+// testClientCert creates a self-signed client certificate, returns it with the cert and the key files
+func testClientCert(t *testing.T) (cert *x509.Certificate, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, testWritePEM(t, "client.pem", "CERTIFICATE", der), testWritePEM(t, "client.key", "EC PRIVATE KEY", keyDer)
+}
+
+//nolint:funlen
+func Test_RunnerTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	caFile := testWritePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	clientCert, certFile, keyFile := testClientCert(t)
+	mtlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	mtlsServer.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: x509.NewCertPool()}
+	mtlsServer.TLS.ClientCAs.AddCert(clientCert)
+	mtlsServer.StartTLS()
+	defer mtlsServer.Close()
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	cases := []struct {
+		name   string
+		url    string
+		config Config
+		reason model.ErrorReason // empty if the probe succeeds
+	}{
+		{"unknown CA", server.URL, Config{}, model.ReasonCertificate},
+		{"CA bundle", server.URL, Config{CAFile: caFile}, ""},
+		{"insecure", server.URL, Config{InsecureSkipVerify: true}, ""},
+		{"server name", server.URL, Config{CAFile: caFile, ServerName: "example.com"}, ""},
+		{"wrong server name", server.URL, Config{CAFile: caFile, ServerName: "service.local"}, model.ReasonCertificate},
+		{"min TLS version", server.URL, Config{CAFile: caFile, MinTLSVersion: "1.3"}, ""},
+		{"client certificate", mtlsServer.URL, Config{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile}, ""},
+		{"no client certificate", mtlsServer.URL, Config{InsecureSkipVerify: true}, model.ReasonUnknown},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.Urls = []string{c.url}
+			p, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if succ := p.Start(ctx); succ != (c.reason == "") {
+				t.Fatalf("probe returned %v, errors %v", succ, p.Result().Errors)
+			}
+			if reason := p.Result().Errors[c.url].Reason; reason != c.reason {
+				t.Errorf("error reason should be %q, got %q", c.reason, reason)
+			}
+		})
+	}
+}
+
+//nolint:funlen
+func Test_RunnerRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/one":
+			http.Redirect(w, r, "/two", http.StatusFound)
+		case "/two":
+			http.Redirect(w, r, server.URL+"/final", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	redirects := func(n Redirects) *Redirects { return &n }
+
+	cases := []struct {
+		name     string
+		config   Config
+		reason   model.ErrorReason
+		finalURL string
+	}{
+		{"followed by default", Config{HTTPStatus: http.StatusOK}, "", ""},
+		{"not followed", Config{HTTPStatus: http.StatusFound, FollowRedirects: redirects(0)}, "", server.URL + "/one"},
+		{"max redirects", Config{FollowRedirects: redirects(2), ExpectedURL: server.URL + "/final"}, "", server.URL + "/final"},
+		{"too many redirects", Config{FollowRedirects: redirects(1)}, model.ReasonRedirect, ""},
+		{"wrong final url", Config{ExpectedURL: server.URL + "/two"}, model.ReasonRedirect, server.URL + "/final"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := server.URL + "/one"
+			c.config.Urls = []string{u}
+			p, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if succ := p.Start(ctx); succ != (c.reason == "") {
+				t.Fatalf("probe returned %v, errors %v", succ, p.Result().Errors)
+			}
+			if reason := p.Result().Errors[u].Reason; reason != c.reason {
+				t.Errorf("error reason should be %q, got %q", c.reason, reason)
+			}
+			if finalURL := p.Result().Data.(ResultData).FinalURLs[u]; finalURL != c.finalURL {
+				t.Errorf("final url should be %q, got %q", c.finalURL, finalURL)
+			}
+		})
+	}
+}
+
+func Test_RunnerMaxRedirects(t *testing.T) {
+	// /n is redirected to /n+1 until /DefaultMaxRedirects
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if n < DefaultMaxRedirects {
+			http.Redirect(w, r, "/"+strconv.Itoa(n+1), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	follow := Redirects(DefaultMaxRedirects)
+	for name, config := range map[string]Config{"default": {}, "followRedirects true": {FollowRedirects: &follow}} {
+		for start, succ := range map[int]bool{0: true, -1: false} {
+			u := server.URL + "/" + strconv.Itoa(start)
+			config.Urls = []string{u}
+			p := New(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, config)
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, name))
+			if p.Start(ctx) != succ {
+				t.Errorf("%v: %v redirects should return %v, errors %v", name, DefaultMaxRedirects-start, succ, p.Result().Errors)
+			}
+		}
+	}
+}
+
+func Test_RedirectsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Redirects
+		wantErr bool
+	}{
+		{"true", DefaultMaxRedirects, false},
+		{"false", 0, false},
+		{"3", 3, false},
+		{"-1", 0, true},
+		{`"yes"`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var r Redirects
+			err := json.Unmarshal([]byte(tt.data), &r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if r != tt.want {
+				t.Errorf("UnmarshalJSON() = %v, want %v", r, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ConstructorWrongTLS(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]Config{
+		"missing CA bundle":     {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"empty CA bundle":       {CAFile: notPEM},
+		"cert without key":      {CertFile: notPEM},
+		"wrong client cert":     {CertFile: notPEM, KeyFile: notPEM},
+		"wrong min TLS version": {MinTLSVersion: "1.4"},
+	}
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			config.Urls = []string{"https://example.com/"}
+			if _, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Second, Expect: true}, config); err == nil {
+				t.Fatal("constructor should return an error")
+			}
+		})
+	}
+}
+
+//nolint:funlen
+func Test_RunnerFanOut(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer tlsServer.Close()
+	caFile := testWritePEM(t, "ca.pem", "CERTIFICATE", tlsServer.Certificate().Raw)
+	_, tlsPort, _ := net.SplitHostPort(tlsServer.Listener.Addr().String())
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	backendURL := "http://backend.test:" + port + "/"
+	cases := []struct {
+		name    string
+		url     string
+		config  Config
+		succ    bool
+		targets []string
+	}{
+		{
+			name:    "resolve keeps the host",
+			url:     backendURL,
+			config:  Config{Resolve: map[string][]string{"backend.test": {"127.0.0.1"}}, Regex: `host: backend.test:` + port + `\n`},
+			succ:    true,
+			targets: []string{backendURL + "@127.0.0.1"},
+		},
+		{
+			name:    "all backends required",
+			url:     backendURL,
+			config:  Config{Resolve: map[string][]string{"backend.test": {"127.0.0.1", "127.0.0.2"}}},
+			succ:    false,
+			targets: []string{backendURL + "@127.0.0.1", backendURL + "@127.0.0.2"},
+		},
+		{
+			name:    "quorum",
+			url:     backendURL,
+			config:  Config{Resolve: map[string][]string{"backend.test": {"127.0.0.1", "127.0.0.2"}}, Quorum: 1},
+			succ:    true,
+			targets: []string{backendURL + "@127.0.0.1", backendURL + "@127.0.0.2"},
+		},
+		{
+			name:    "fan out of resolved ips",
+			url:     server.URL,
+			config:  Config{FanOut: true},
+			succ:    true,
+			targets: []string{server.URL + "@127.0.0.1"},
+		},
+		{
+			name:    "tls server name",
+			url:     "https://example.com:" + tlsPort,
+			config:  Config{Resolve: map[string][]string{"example.com": {"127.0.0.1"}}, CAFile: caFile},
+			succ:    true,
+			targets: []string{"https://example.com:" + tlsPort + "@127.0.0.1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.Urls = []string{c.url}
+			p, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if succ := p.Start(ctx); succ != c.succ {
+				t.Fatalf("probe returned %v, errors %v", succ, p.Result().Errors)
+			}
+			var targets []string
+			for target := range p.Result().Data.(ResultData).Timings {
+				targets = append(targets, target)
+			}
+			for target := range p.Result().Errors {
+				targets = append(targets, target)
+			}
+			sort.Strings(targets)
+			if !reflect.DeepEqual(targets, c.targets) {
+				t.Errorf("targets = %v, want %v", targets, c.targets)
+			}
+		})
+	}
+}
+
+func Test_RunnerQuorumResolvedOnly(t *testing.T) {
+	// the backends listen on every loopback ip
+	l, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	// the quorum of the fanned-out url doesn't apply to the url sent as is
+	config := Config{
+		Urls:    []string{"http://backend.test:" + port + "/", "http://127.0.0.1:" + port + "/"},
+		Resolve: map[string][]string{"backend.test": {"127.0.0.1", "127.0.0.2"}},
+		Quorum:  2,
+	}
+	p, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, config)
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "quorum"))
+	if !p.Start(ctx) {
+		t.Fatalf("probe should succeed, errors %v", p.Result().Errors)
+	}
+}
+
+func Test_RunnerClosesBackendConnections(t *testing.T) {
+	var closed atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	config := Config{
+		Urls:    []string{"http://backend.test:" + port + "/"},
+		Resolve: map[string][]string{"backend.test": {"127.0.0.1"}},
+	}
+	if err := config.compileResolve(); err != nil {
+		t.Fatal(err)
+	}
+	p := New(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, config)
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "connections"))
+	if !p.Start(ctx) {
+		t.Fatalf("probe should succeed, errors %v", p.Result().Errors)
+	}
+	// the idle connection of the transport created for the backend isn't kept after the run
+	for i := 0; i < 100 && closed.Load() == 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if closed.Load() != 1 {
+		t.Fatal("the backend connection should be closed after the run")
+	}
+}
+
+func Test_ConstructorWrongResolve(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+
+	cases := map[string]Config{
+		"negative quorum":         {FanOut: true, Quorum: -1},
+		"quorum without fan out":  {Quorum: 2},
+		"unknown host":            {Resolve: map[string][]string{"example.org": {"127.0.0.1"}}},
+		"no ips":                  {Resolve: map[string][]string{"example.com": {}}},
+		"wrong ip":                {Resolve: map[string][]string{"example.com": {"example.org"}}},
+		"quorum over resolve ips": {Resolve: map[string][]string{"example.com": {"127.0.0.1"}}, Quorum: 2},
+	}
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			config.Urls = []string{"https://example.com/"}
+			if _, err := constructor.NewProbe(model.ProbeOptions{Timeout: time.Second, Expect: true}, config); err == nil {
+				t.Fatal("constructor should return an error")
+			}
+		})
+	}
+}
+
+func Test_parseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []any
+		wantErr bool
+	}{
+		{"$", nil, false},
+		{"$.queue.depth", []any{"queue", "depth"}, false},
+		{"queue.depth", []any{"queue", "depth"}, false},
+		{"$.items[1].status", []any{"items", 1, "status"}, false},
+		{`$["a.b"]['c']`, []any{"a.b", "c"}, false},
+		{"$[0]", []any{0}, false},
+		{"$.items[-1]", nil, true},
+		{"$.items[0", nil, true},
+		{"$..a", nil, true},
+		{"$a", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSONPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+//nolint:funlen
+func Test_JSONAssertionCheck(t *testing.T) {
+	var body any
+	_ = json.Unmarshal([]byte(`{"status":"ok","queue":{"depth":12},"items":[{"id":1},{"id":2}],"empty":null}`), &body)
+	tests := []struct {
+		name      string
+		assertion JSONAssertion
+		value     any
+		wantErr   bool
+	}{
+		{"equals string", JSONAssertion{Path: "$.status", Op: JSONOpEquals, Value: "ok"}, "ok", false},
+		{"equals number", JSONAssertion{Path: "$.queue.depth", Op: JSONOpEquals, Value: 12}, float64(12), false},
+		{"equals fails", JSONAssertion{Path: "$.status", Op: JSONOpEquals, Value: "failed"}, "ok", true},
+		{"equals object", JSONAssertion{Path: "$.items[0]", Op: JSONOpEquals, Value: map[string]any{"id": 1}}, map[string]any{"id": float64(1)}, false},
+		{"not equals", JSONAssertion{Path: "$.status", Op: JSONOpNotEquals, Value: "failed"}, "ok", false},
+		{"lt", JSONAssertion{Path: "$.queue.depth", Op: JSONOpLt, Value: 100}, float64(12), false},
+		{"lt fails", JSONAssertion{Path: "$.queue.depth", Op: JSONOpLt, Value: 12}, float64(12), true},
+		{"gt", JSONAssertion{Path: "$.queue.depth", Op: JSONOpGt, Value: 10.5}, float64(12), false},
+		{"gt of a string", JSONAssertion{Path: "$.status", Op: JSONOpGt, Value: 1}, "ok", true},
+		{"exists", JSONAssertion{Path: "$.items[1].id", Op: JSONOpExists}, float64(2), false},
+		{"exists null", JSONAssertion{Path: "$.empty", Op: JSONOpExists}, nil, false},
+		{"exists fails", JSONAssertion{Path: "$.items[2]", Op: JSONOpExists}, nil, true},
+		{"doesn't exist", JSONAssertion{Path: "$.error", Op: JSONOpExists, Value: false}, nil, false},
+		{"regex", JSONAssertion{Path: "$.status", Op: JSONOpRegex, Value: "^(ok|degraded)$"}, "ok", false},
+		{"regex of a number", JSONAssertion{Path: "$.queue.depth", Op: JSONOpRegex, Value: `^\d+$`}, float64(12), false},
+		{"length", JSONAssertion{Path: "$.items", Op: JSONOpLength, Value: 2}, 2, false},
+		{"length fails", JSONAssertion{Path: "$.items", Op: JSONOpLength, Value: 3}, 2, true},
+		{"length of a number", JSONAssertion{Path: "$.queue.depth", Op: JSONOpLength, Value: 2}, float64(12), true},
+		{"not found", JSONAssertion{Path: "$.queue.size", Op: JSONOpEquals, Value: 1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.assertion.compile(); err != nil {
+				t.Fatal(err)
+			}
+			value, err := tt.assertion.check(body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(value, tt.value) {
+				t.Errorf("check() value = %#v, want %#v", value, tt.value)
+			}
+		})
+	}
+}
+
+func Test_JSONAssertionCompile(t *testing.T) {
+	for name, a := range map[string]JSONAssertion{
+		"unknown operator":  {Path: "$.a", Op: "contains", Value: 1},
+		"lt without number": {Path: "$.a", Op: JSONOpLt, Value: "1"},
+		"wrong regex":       {Path: "$.a", Op: JSONOpRegex, Value: "["},
+		"wrong exists":      {Path: "$.a", Op: JSONOpExists, Value: 1},
+		"wrong path":        {Path: "$.a[", Op: JSONOpExists},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := a.compile(); err == nil {
+				t.Error("compile() should return an error")
+			}
+		})
+	}
+}
+
+func Test_RunnerJSONAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		depth := "12"
+		if r.URL.Path == "/busy" {
+			depth = "1500"
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","queue_depth":` + depth + `,"workers":["a","b"]}`))
+	}))
+	defer server.Close()
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	idle, busy := server.URL+"/idle", server.URL+"/busy"
+	p, err := constructor.NewProbe(
+		model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true},
+		Config{
+			Urls: []string{idle, busy},
+			JSONAssertions: []JSONAssertion{
+				{Path: "$.status", Op: JSONOpEquals, Value: "ok"},
+				{Name: "queue_depth", Path: "$.queue_depth", Op: JSONOpLt, Value: 1000},
+				{Name: "workers", Path: "$.workers", Op: JSONOpLength, Value: 2},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "json assertions"))
+	if p.Start(ctx) {
+		t.Fatal("probe should return false because the queue of the busy url is too deep")
+	}
+	result := p.Result()
+	data := result.Data.(ResultData)
+	wantValues := map[string]map[string]any{
+		idle: {"$.status": "ok", "queue_depth": float64(12), "workers": 2},
+		busy: {"$.status": "ok", "queue_depth": float64(1500), "workers": 2},
+	}
+	if !reflect.DeepEqual(data.JSON, wantValues) {
+		t.Errorf("json values = %v, want %v", data.JSON, wantValues)
+	}
+	wantAssertions := map[string]map[string]bool{
+		idle: {"$.status": true, "queue_depth": true, "workers": true},
+		busy: {"$.status": true, "queue_depth": false, "workers": true},
+	}
+	if !reflect.DeepEqual(data.JSONAssertions, wantAssertions) {
+		t.Errorf("json assertions = %v, want %v", data.JSONAssertions, wantAssertions)
+	}
+	if _, ok := result.Errors[idle]; ok {
+		t.Error("the idle url shouldn't fail")
+	}
+	if reason := result.Errors[busy].Reason; reason != model.ReasonMismatch {
+		t.Errorf("error reason should be %q, got %q", model.ReasonMismatch, reason)
+	}
+}
+
+func Test_ConstructorWrongJSONAssertions(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	_, err := constructor.NewProbe(
+		model.ProbeOptions{Timeout: time.Second, Expect: true},
+		Config{
+			Urls: []string{"https://example.com/"},
+			JSONAssertions: []JSONAssertion{
+				{Path: "$.a", Op: JSONOpExists},
+				{Name: "$.a", Path: "$.b", Op: JSONOpExists},
+			},
+		},
+	)
+	if err == nil {
+		t.Fatal("constructor should return an error for duplicate assertion names")
+	}
+}
+
+func Test_phaseTracer(t *testing.T) {
+	base := time.Now()
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	p := phaseTracer{
+		connectStart: at(0), connectDone: at(2),
+		wroteRequest: at(3), firstByte: at(13),
+		bodyRead: at(14),
+	}
+	want := map[string]float64{PhaseConnect: 2, PhaseTTFB: 10, PhaseTransfer: 1}
+	got := p.phases()
+	if len(got) != len(want) {
+		t.Fatalf("phases() = %v, want %v", got, want)
+	}
+	for phase, ms := range want {
+		if got[phase] != ms {
+			t.Errorf("phase %v = %v, want %v", phase, got[phase], ms)
+		}
+	}
+}
+
+func Test_RunnerPhases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("first part\n"))
+		w.(http.Flusher).Flush()
+		time.Sleep(30 * time.Millisecond)
+		_, _ = w.Write([]byte("second part\n"))
+	}))
+	defer server.Close()
+
+	p := New(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, Config{Urls: []string{server.URL}, TraceTransfer: true})
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "phases"))
+	if !p.Start(ctx) {
+		t.Fatal("probe should return true")
+	}
+	phases := p.Result().Data.(ResultData).Phases[server.URL]
+	if _, ok := phases[PhaseConnect]; !ok {
+		t.Errorf("connect phase should be traced, got %v", phases)
+	}
+	if _, ok := phases[PhaseTLS]; ok {
+		t.Errorf("tls phase shouldn't be traced for http, got %v", phases)
+	}
+	if phases[PhaseTTFB] < 50 {
+		t.Errorf("ttfb phase should be at least 50ms, got %v", phases[PhaseTTFB])
+	}
+	if phases[PhaseTransfer] < 30 {
+		t.Errorf("transfer phase should be at least 30ms, got %v", phases[PhaseTransfer])
+	}
+}
+
+func Test_RunnerPhasesRedirect(t *testing.T) {
+	final := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer final.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		http.Redirect(w, r, final.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	p := New(model.ProbeOptions{Timeout: time.Millisecond * 5000, Expect: true}, Config{Urls: []string{server.URL}})
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "redirect"))
+	if !p.Start(ctx) {
+		t.Fatalf("probe should return true, errors %v", p.Result().Errors)
+	}
+	// the phases are the ones of the request to the final server
+	phases := p.Result().Data.(ResultData).Phases[server.URL]
+	if connect, ok := phases[PhaseConnect]; !ok || connect >= 100 {
+		t.Errorf("connect phase of the last request should be traced, got %v", phases)
+	}
+	if phases[PhaseTTFB] >= 100 {
+		t.Errorf("ttfb phase of the last request should be traced, got %v", phases)
+	}
+}
+
+func Test_RunnerTransferNotTraced(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			_, _ = w.Write(make([]byte, maxDrainSize+1))
+			return
+		}
+		// a stream which isn't finished until the client timeout
+		_, _ = w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	cases := map[string]Config{
+		"stream isn't read": {Urls: []string{server.URL + "/stream"}},
+		"large body":        {Urls: []string{server.URL + "/large"}, TraceTransfer: true},
+		"unfinished stream": {Urls: []string{server.URL + "/stream"}, TraceTransfer: true},
+	}
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := New(model.ProbeOptions{Timeout: time.Millisecond * 300, Expect: true}, config)
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, name))
+			if !p.Start(ctx) {
+				t.Fatalf("probe should return true, errors %v", p.Result().Errors)
+			}
+			phases := p.Result().Data.(ResultData).Phases[config.Urls[0]]
+			if _, ok := phases[PhaseTTFB]; !ok {
+				t.Errorf("ttfb phase should be traced, got %v", phases)
+			}
+			if _, ok := phases[PhaseTransfer]; ok {
+				t.Errorf("transfer phase shouldn't be traced, got %v", phases)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package xraySSConnect
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+)
+
+type constructor struct {
+	probefactory.BaseConstructor
+}
+
+func (c constructor) NewProbe(options model.ProbeOptions, configuration any) (p model.Prober, err error) {
+	var config Config
+	if config, err = c.configuration(configuration); err != nil {
+		return
+	}
+
+	return New(options, config), nil
+}
+
+func (c constructor) NewProbeConfiguration() any {
+	return c.SetConfigDefaults(&Config{})
+}
+
+// configuration casts configuration of any type to Config struct
+func (c constructor) configuration(data any) (config Config, err error) {
+	if data == nil {
+		err = model.ErrorConfig
+		return
+	}
+
+	switch v := data.(type) {
+	case *Config:
+		config = *v
+	case Config:
+		config = v
+	case string:
+		config = *(c.NewProbeConfiguration().(*Config))
+		config.ConfigFile = v
+	default:
+		err = model.ErrorConfig
+		return
+	}
+
+	if config.ConfigFile == "" && config.ConfigData == "" {
+		err = model.ErrorConfig
+		return
+	}
+
+	configData := config.ConfigData
+	if config.ConfigFile != "" {
+		var b []byte
+		if b, err = os.ReadFile(config.ConfigFile); err != nil {
+			err = fmt.Errorf("can't load xray configuration from file %v: %w", config.ConfigFile, err)
+			return
+		}
+		configData = string(b)
+	}
+
+	if config.SocksAddr == "" {
+		config.SocksAddr = socksAddrFromConfigData(configData)
+		if config.SocksAddr == "" {
+			err = fmt.Errorf("can't get local socks listener address from xray configuration")
+			return
+		}
+	} else if _, _, e := net.SplitHostPort(config.SocksAddr); e != nil {
+		err = fmt.Errorf("wrong socksAddr %v: %w", config.SocksAddr, e)
+		return
+	}
+
+	if config.CheckURL != "" {
+		if _, e := url.Parse(config.CheckURL); e != nil {
+			err = fmt.Errorf("wrong checkURL %v: %w", config.CheckURL, e)
+		}
+	}
+	return
+}
@@ -1 +1,303 @@
 package xraySSConnect
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"boogieman/src/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-cmd/cmd"
+)
+
+var BinaryPath = "xray"
+
+// ConfigPathPlaceholder is replaced with the path to the client configuration file in Args
+const ConfigPathPlaceholder = "{config}"
+
+type Probe struct {
+	model.ProbeHandler
+	Config `json:"config"`
+	cmd    *cmd.Cmd
+}
+
+type Config struct {
+	ConfigFile string   // path to xray/shadowsocks client configuration file
+	ConfigData string   // xray/shadowsocks client configuration
+	Cmd        string   `json:"cmd,omitempty"`        // tunnel client binary, BinaryPath is used by default
+	Args       []string `json:"args,omitempty"`       // tunnel client arguments, "run -c {config}" by default
+	SocksAddr  string   `json:"socksAddr,omitempty"`  // local socks listener, is read from the client configuration by default
+	CheckURL   string   `json:"checkURL,omitempty"`   // url to fetch through the tunnel
+	HTTPStatus int      `json:"httpStatus,omitempty"` // expected http status of checkURL
+	LogDump    bool
+}
+
+type ResultData struct {
+	ConnectTime int `json:"connectTime"`
+	CheckTime   int `json:"checkTime,omitempty"`
+	HTTPStatus  int `json:"httpStatus,omitempty"`
+}
+
+var name = "xray"
+
+var (
+	ErrTimeout          = errors.New("timeout")
+	ErrUnexpectedExit   = errors.New("tunnel client exited unexpectedly")
+	socksPollInterval   = 100 * time.Millisecond
+	socksDialTimeout    = 100 * time.Millisecond
+	defaultArgsTemplate = []string{"run", "-c", ConfigPathPlaceholder}
+)
+
+func init() {
+	probefactory.RegisterProbe(constructor{probefactory.BaseConstructor{Name: name}})
+}
+
+func New(options model.ProbeOptions, config Config) *Probe {
+	p := Probe{}
+	p.ProbeOptions = options
+	p.Name = name
+	p.Config = config
+	p.ProbeHandler.Config = config
+	p.CanStayBackground = true
+	p.SetRunner(p.Runner).SetFinisher(p.Finisher)
+	return &p
+}
+
+//nolint:funlen
+func (c *Probe) Runner(ctx context.Context) (succ bool, resultObject any) {
+	var (
+		configFileName string
+		err            error
+		rd             ResultData
+	)
+
+	defer func() {
+		if err != nil {
+			c.Log("[%v] %v, %vms", c.SocksAddr, err, c.Duration().Milliseconds())
+			c.SetError(err)
+			c.SetTargetError(c.SocksAddr, err)
+		} else {
+			c.Log("[%v] OK, %vms", c.SocksAddr, c.Duration().Milliseconds())
+		}
+	}()
+
+	if c.cmd != nil {
+		err = fmt.Errorf("another tunnel client is still running by this probe")
+		return
+	}
+
+	if c.ConfigFile != "" {
+		configFileName = c.ConfigFile
+	} else {
+		configFileName, err = util.StringToFile(c.ConfigData)
+		if err != nil {
+			err = fmt.Errorf("can't create temporary file with xray config: %w", err)
+			return
+		}
+		defer func() {
+			e := syscall.Unlink(configFileName)
+			if e != nil {
+				c.Log("can't remove tmpFile %v: %v", configFileName, e)
+			}
+		}()
+	}
+
+	t := time.Now()
+	bin, args := c.command(configFileName)
+	c.cmd, err = xrayStart(ctx, bin, args, c.SocksAddr, c.Timeout, c.LogDump)
+	if err == nil {
+		rd.ConnectTime = int(time.Since(t).Milliseconds())
+		if c.CheckURL != "" {
+			err = c.check(ctx, &rd)
+		}
+	}
+	resultObject = rd
+
+	succ = err == nil
+
+	if !c.StayBackground || !succ {
+		c.Finish(ctx)
+	} else {
+		// continue to read stdout/stderr of running process until channel closing
+		go func(cmd *cmd.Cmd) {
+			var line string
+			ok := true
+			for ok {
+				select {
+				case line, ok = <-cmd.Stdout:
+				case line, ok = <-cmd.Stderr:
+				}
+				if ok && c.LogDump && line != "" {
+					log.Print(line)
+				}
+			}
+			// channel is closed
+			c.Finish(ctx)
+		}(c.cmd)
+	}
+
+	return
+}
+
+func (c *Probe) Finisher(context.Context) {
+	if c.cmd != nil {
+		err := c.cmd.Stop()
+		if err != nil {
+			c.Log("unexpected error on stopping tunnel client process")
+		}
+		c.cmd = nil
+	}
+}
+
+func (c *Probe) IsAlive() bool {
+	return c.cmd != nil
+}
+
+// command returns the tunnel client binary and arguments with substituted configuration file path
+func (c *Probe) command(configPath string) (bin string, args []string) {
+	bin = c.Cmd
+	if bin == "" {
+		bin = BinaryPath
+	}
+	argsTemplate := c.Args
+	if len(argsTemplate) == 0 {
+		argsTemplate = defaultArgsTemplate
+	}
+	for _, a := range argsTemplate {
+		args = append(args, strings.ReplaceAll(a, ConfigPathPlaceholder, configPath))
+	}
+	return
+}
+
+// check fetches CheckURL through the tunnel socks listener
+func (c *Probe) check(ctx context.Context, rd *ResultData) (err error) {
+	t := time.Now()
+	client := http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyURL(&url.URL{Scheme: "socks5", Host: c.SocksAddr}),
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.CheckURL, nil)
+	if err != nil {
+		return
+	}
+	r, err := client.Do(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "context deadline exceeded") {
+			return ErrTimeout
+		}
+		return fmt.Errorf("http error through the tunnel %w", err)
+	}
+	_ = r.Body.Close()
+	rd.CheckTime = int(time.Since(t).Milliseconds())
+	rd.HTTPStatus = r.StatusCode
+	if c.HTTPStatus != 0 && r.StatusCode != c.HTTPStatus {
+		return fmt.Errorf("wrong response %v", r.StatusCode)
+	}
+	return
+}
+
+// xrayStart starts the tunnel client process and waits until the local socks listener accepts connections
+// or error | timeout happened, returns cmd.Cmd describing running tunnel client or error
+func xrayStart(ctx context.Context, bin string, args []string, socksAddr string, initTimeout time.Duration, logout bool) (cmdRunner *cmd.Cmd, err error) {
+	cmdRunner = cmd.NewCmdOptions(cmd.Options{Buffered: true, Streaming: true}, bin, args...)
+
+	status := cmdRunner.Start()
+
+	var finished cmd.Status
+	timer := time.After(initTimeout)
+	ticker := time.NewTicker(socksPollInterval)
+	defer ticker.Stop()
+	ready := false
+	for finished.Runtime == 0 && err == nil && !ready {
+		select {
+		case finished = <-status:
+			break
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timer:
+			err = ErrTimeout
+			break
+		case <-ticker.C:
+			if conn, e := net.DialTimeout("tcp", socksAddr, socksDialTimeout); e == nil {
+				_ = conn.Close()
+				ready = true
+			}
+		case line := <-cmdRunner.Stdout:
+			if logout {
+				log.Print(line)
+			}
+		case line := <-cmdRunner.Stderr:
+			if logout {
+				log.Print(line)
+			}
+		}
+	}
+
+	// stop process on errors
+	if err != nil {
+		if e := cmdRunner.Stop(); e != nil {
+			log.Printf("unexpected error on stopping tunnel client process: %v", e)
+		}
+	}
+
+	if err == nil && !ready {
+		if finished.Error != nil {
+			err = fmt.Errorf("can't start tunnel client: %w", finished.Error)
+		} else {
+			err = fmt.Errorf("%w with code %v", ErrUnexpectedExit, finished.Exit)
+		}
+	}
+
+	return
+}
+
+// socksAddrFromConfigData returns the local socks listener address from xray (inbounds)
+// or shadowsocks (local_address, local_port) json configuration
+func socksAddrFromConfigData(configData string) string {
+	var conf struct {
+		Inbounds []struct {
+			Listen   string          `json:"listen"`
+			Port     json.RawMessage `json:"port"`
+			Protocol string          `json:"protocol"`
+		} `json:"inbounds"`
+		LocalAddress string `json:"local_address"`
+		LocalPort    int    `json:"local_port"`
+	}
+	if err := json.Unmarshal([]byte(configData), &conf); err != nil {
+		return ""
+	}
+	for _, in := range conf.Inbounds {
+		if in.Protocol != "socks" {
+			continue
+		}
+		port := strings.Trim(string(in.Port), `"`)
+		if _, err := strconv.Atoi(port); err != nil {
+			continue
+		}
+		return net.JoinHostPort(listenHost(in.Listen), port)
+	}
+	if conf.LocalPort != 0 {
+		return net.JoinHostPort(listenHost(conf.LocalAddress), strconv.Itoa(conf.LocalPort))
+	}
+	return ""
+}
+
+// listenHost returns the address to connect to the listener bound to host
+func listenHost(host string) string {
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		return "127.0.0.1"
+	}
+	return host
+}
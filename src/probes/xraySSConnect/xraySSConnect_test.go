@@ -0,0 +1,296 @@
+package xraySSConnect
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	standInEnv         = "BOOGIEMAN_XRAY_STANDIN"
+	standInModeServe   = "serve"
+	standInModeExit    = "exit"
+	standInModeNoSocks = "nosocks"
+)
+
+// TestMain runs the test binary as a tunnel client stand-in if standInEnv is set,
+// the stand-in reads the xray configuration and serves a plain socks5 proxy on the inbound address
+func TestMain(m *testing.M) {
+	switch os.Getenv(standInEnv) {
+	case "":
+		BinaryPath = os.Args[0]
+		os.Exit(m.Run())
+	case standInModeExit:
+		os.Exit(1)
+	case standInModeNoSocks:
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	case standInModeServe:
+		os.Exit(standInServe())
+	}
+}
+
+func standInServe() int {
+	var configPath string
+	for i, a := range os.Args {
+		if a == "-c" && i+1 < len(os.Args) {
+			configPath = os.Args[i+1]
+		}
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	l, err := net.Listen("tcp", socksAddrFromConfigData(string(data)))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return 1
+		}
+		go socks5Serve(conn)
+	}
+}
+
+// socks5Serve handles a socks5 CONNECT request without authentication
+func socks5Serve(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 262)
+	// greeting: VER NMETHODS METHODS
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte{0x05, 0x00})
+	// request: VER CMD RSV ATYP
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 0x01:
+		_, _ = io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 0x03:
+		_, _ = io.ReadFull(conn, buf[:1])
+		n := int(buf[0])
+		_, _ = io.ReadFull(conn, buf[:n])
+		host = string(buf[:n])
+	case 0x04:
+		_, _ = io.ReadFull(conn, buf[:16])
+		host = net.IP(buf[:16]).String()
+	}
+	_, _ = io.ReadFull(conn, buf[:2])
+	port := binary.BigEndian.Uint16(buf[:2])
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		_, _ = conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	_, _ = conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	go func() { _, _ = io.Copy(target, conn) }()
+	_, _ = io.Copy(conn, target)
+}
+
+func testFreeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	return addr
+}
+
+func testXrayConfig(addr string) string {
+	host, port, _ := net.SplitHostPort(addr)
+	return fmt.Sprintf(`{"inbounds":[{"listen":"%v","port":%v,"protocol":"socks"}],"outbounds":[{"protocol":"shadowsocks"}]}`, host, port)
+}
+
+func Test_Runner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	options := model.ProbeOptions{Timeout: time.Millisecond * 2000, Expect: true}
+
+	type testCase struct {
+		name           string
+		mode           string
+		config         Config
+		expectedResult bool
+	}
+
+	cases := []testCase{
+		{
+			"socks listener becomes ready",
+			standInModeServe,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t))},
+			true,
+		},
+		{
+			"check url is fetched through the tunnel",
+			standInModeServe,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t)), CheckURL: server.URL, HTTPStatus: http.StatusOK},
+			true,
+		},
+		{
+			"check url returns unexpected status",
+			standInModeServe,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t)), CheckURL: server.URL, HTTPStatus: http.StatusNotFound},
+			false,
+		},
+		{
+			"check url isn't reachable through the tunnel",
+			standInModeServe,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t)), CheckURL: "http://" + testFreeAddr(t)},
+			false,
+		},
+		{
+			"tunnel client exits on startup",
+			standInModeExit,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t))},
+			false,
+		},
+		{
+			"socks listener isn't opened until timeout",
+			standInModeNoSocks,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t))},
+			false,
+		},
+		{
+			"tunnel client binary doesn't exist",
+			standInModeServe,
+			Config{ConfigData: testXrayConfig(testFreeAddr(t)), Cmd: "/not/exists/xray"},
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(standInEnv, c.mode)
+			p, err := constructor.NewProbe(options, c.config)
+			if err != nil {
+				t.Fatalf("constructor returned error: %v", err)
+			}
+			ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, c.name))
+			if p.Start(ctx) != c.expectedResult {
+				t.Fatalf("probe should return %v", c.expectedResult)
+			}
+			if p.IsAlive() {
+				t.Fatal("tunnel client should be stopped without stayBackground")
+			}
+		})
+	}
+}
+
+func Test_RunnerStayBackground(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	t.Setenv(standInEnv, standInModeServe)
+
+	addr := testFreeAddr(t)
+	configFile := t.TempDir() + "/xray.json"
+	if err := os.WriteFile(configFile, []byte(testXrayConfig(addr)), 0600); err != nil {
+		t.Fatalf("can't write config: %v", err)
+	}
+
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	p, err := constructor.NewProbe(
+		model.ProbeOptions{Timeout: time.Millisecond * 2000, Expect: true, StayBackground: true},
+		configFile,
+	)
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+	ctx := model.ContextWithLogger(context.Background(), model.NewChainLogger(model.DefaultLogger, "stay background"))
+	if !p.Start(ctx) {
+		t.Fatal("probe should return true")
+	}
+	if !p.IsAlive() {
+		t.Fatal("tunnel client should stay alive")
+	}
+
+	// the tunnel is still usable by later tasks
+	client := http.Client{
+		Timeout:   time.Second,
+		Transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "socks5", Host: addr})},
+	}
+	r, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request through the background tunnel failed: %v", err)
+	}
+	_ = r.Body.Close()
+
+	p.Finish(ctx)
+	if p.IsAlive() {
+		t.Fatal("tunnel client should be stopped on finish")
+	}
+
+	data, ok := p.Result().Data.(ResultData)
+	if !ok {
+		t.Fatalf("probe data should be ResultData, got %T", p.Result().Data)
+	}
+	if data.ConnectTime <= 0 {
+		t.Fatalf("connect time should be exported, got %v", data.ConnectTime)
+	}
+}
+
+func Test_Constructor(t *testing.T) {
+	constructor := constructor{
+		probefactory.BaseConstructor{
+			Name: name,
+		},
+	}
+	options := model.ProbeOptions{Timeout: time.Millisecond * 1000, Expect: true}
+
+	p, err := constructor.NewProbe(options, Config{ConfigData: `{"local_address":"0.0.0.0","local_port":1086}`})
+	if err != nil {
+		t.Fatalf("constructor returned error: %v", err)
+	}
+	if addr := p.(*Probe).SocksAddr; addr != "127.0.0.1:1086" {
+		t.Fatalf("socks address should be read from shadowsocks config, got %v", addr)
+	}
+
+	wrongConfigs := []any{
+		Config{},
+		Config{ConfigData: `{"inbounds":[{"port":1080,"protocol":"http"}]}`},
+		Config{ConfigData: "{}", SocksAddr: "127.0.0.1"},
+		"test/not-exists.json",
+	}
+	for i, c := range wrongConfigs {
+		if _, err = constructor.NewProbe(options, c); err == nil {
+			t.Errorf("constructor should return an error for config %v", i)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package history
+
+import (
+	"boogieman/src/model"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// compactMinLines is the number of outdated lines the file can have before it's rewritten
+const compactMinLines = 1000
+
+// record is a line of the jsonl file
+type record struct {
+	Job    string             `json:"job"`
+	Result model.ScriptResult `json:"result"`
+}
+
+// JSONL appends the results to a file with a json record per line, the results are loaded on start,
+// the file is rewritten without the outdated records when they exceed the number of the kept ones
+type JSONL struct {
+	*Memory
+	fileName string
+	file     *os.File
+	lines    int
+	lock     sync.Mutex
+}
+
+func NewJSONL(fileName string, retention Retention) (s *JSONL, err error) {
+	s = &JSONL{Memory: NewMemory(retention), fileName: fileName}
+	if err = s.load(); err != nil {
+		return nil, err
+	}
+	if err = s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *JSONL) load() error {
+	f, err := os.Open(s.fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var r record
+			// a broken line, e.g. the last one written on a crash, is skipped
+			if json.Unmarshal(line, &r) == nil {
+				_ = s.Memory.Add(r.Job, r.Result)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// compact rewrites the file with the kept records and opens it for appending
+func (s *JSONL) compact() (err error) {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	records := s.Memory.records()
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for _, r := range records {
+		if err = encoder.Encode(r); err != nil {
+			return
+		}
+	}
+	tmp := s.fileName + ".tmp"
+	if err = os.WriteFile(tmp, b.Bytes(), 0o644); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, s.fileName); err != nil {
+		return
+	}
+	s.lines = len(records)
+	s.file, err = os.OpenFile(s.fileName, os.O_WRONLY|os.O_APPEND, 0o644)
+	return
+}
+
+func (s *JSONL) Add(job string, r model.ScriptResult) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	line, err := json.Marshal(record{Job: job, Result: r})
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.lines++
+	_ = s.Memory.Add(job, r)
+	if s.lines-s.Memory.count() > compactMinLines {
+		return s.compact()
+	}
+	return nil
+}
+
+func (s *JSONL) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
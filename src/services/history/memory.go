@@ -0,0 +1,121 @@
+package history
+
+import (
+	"boogieman/src/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory keeps the last results of every job in a ring buffer
+type Memory struct {
+	retention Retention
+	jobs      map[string]*ring
+	sync.Mutex
+}
+
+func NewMemory(retention Retention) *Memory {
+	return &Memory{retention: retention, jobs: make(map[string]*ring)}
+}
+
+func (m *Memory) Add(job string, r model.ScriptResult) error {
+	m.Lock()
+	defer m.Unlock()
+	rb, ok := m.jobs[job]
+	if !ok {
+		rb = &ring{}
+		m.jobs[job] = rb
+	}
+	rb.push(r, m.retention.MaxRecords)
+	m.expire(rb)
+	return nil
+}
+
+func (m *Memory) Query(q Query) ([]model.ScriptResult, error) {
+	m.Lock()
+	defer m.Unlock()
+	rb, ok := m.jobs[q.Job]
+	if !ok {
+		return []model.ScriptResult{}, nil
+	}
+	m.expire(rb)
+	return selectResults(rb.list(), q), nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// records returns the results of all jobs ordered by start time
+func (m *Memory) records() (records []record) {
+	m.Lock()
+	defer m.Unlock()
+	for job, rb := range m.jobs {
+		m.expire(rb)
+		for _, r := range rb.list() {
+			records = append(records, record{Job: job, Result: r})
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Result.StartedAt.Before(records[j].Result.StartedAt)
+	})
+	return
+}
+
+// count returns the number of kept results of all jobs
+func (m *Memory) count() (n int) {
+	m.Lock()
+	defer m.Unlock()
+	for _, rb := range m.jobs {
+		n += len(rb.items)
+	}
+	return
+}
+
+// expire removes the results older than MaxAge
+func (m *Memory) expire(rb *ring) {
+	if m.retention.MaxAge <= 0 || len(rb.items) == 0 {
+		return
+	}
+	threshold := time.Now().Add(-m.retention.MaxAge)
+	if !rb.items[rb.start].StartedAt.Before(threshold) {
+		return
+	}
+	items := rb.list()
+	n := 0
+	for n < len(items) && items[n].StartedAt.Before(threshold) {
+		n++
+	}
+	if n > 0 {
+		rb.items, rb.start = items[n:], 0
+	}
+}
+
+// ring is a chronological buffer of results, the oldest result is replaced when the buffer is full
+type ring struct {
+	items []model.ScriptResult
+	start int // index of the oldest result
+}
+
+func (r *ring) push(v model.ScriptResult, size int) {
+	if size > 0 && len(r.items) >= size {
+		if len(r.items) > size {
+			// the size is reduced
+			r.items, r.start = r.list()[len(r.items)-size:], 0
+		}
+		r.items[r.start] = v
+		r.start = (r.start + 1) % size
+		return
+	}
+	if r.start != 0 {
+		r.items, r.start = r.list(), 0
+	}
+	r.items = append(r.items, v)
+}
+
+// list returns a copy of the results from the oldest to the newest
+func (r *ring) list() []model.ScriptResult {
+	l := make([]model.ScriptResult, 0, len(r.items))
+	l = append(l, r.items[r.start:]...)
+	return append(l, r.items[:r.start]...)
+}
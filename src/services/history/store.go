@@ -0,0 +1,82 @@
+package history
+
+import (
+	"boogieman/src/model"
+	"fmt"
+	"time"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreJSONL  = "jsonl"
+
+	DefaultMaxRecords = 100
+)
+
+// Store keeps the finished script results of the jobs
+type Store interface {
+	// Add saves the result of the job run
+	Add(job string, r model.ScriptResult) error
+	// Query returns the job results in chronological order
+	Query(q Query) ([]model.ScriptResult, error)
+	Close() error
+}
+
+// Query selects the job results started within [From, To], zero times aren't limited,
+// the last Limit results are returned if Limit > 0
+type Query struct {
+	Job   string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// Retention limits the stored results, zero values aren't limited
+type Retention struct {
+	MaxRecords int // per job
+	MaxAge     time.Duration
+}
+
+// Options is the configuration of the store
+type Options struct {
+	Store      string // memory or jsonl
+	File       string // jsonl file
+	MaxRecords int
+	MaxAge     string // e.g. 168h
+}
+
+// New creates the store defined by the options
+func New(o Options) (s Store, err error) {
+	var r Retention
+	r.MaxRecords = o.MaxRecords
+	if o.MaxAge != "" {
+		if r.MaxAge, err = time.ParseDuration(o.MaxAge); err != nil {
+			return nil, fmt.Errorf("wrong history max_age: %w", err)
+		}
+	}
+	switch o.Store {
+	case StoreMemory, "":
+		return NewMemory(r), nil
+	case StoreJSONL:
+		if o.File == "" {
+			return nil, fmt.Errorf("history file should be defined for the %v store", o.Store)
+		}
+		return NewJSONL(o.File, r)
+	}
+	return nil, fmt.Errorf("unknown history store %v", o.Store)
+}
+
+// selectResults returns the results matching the query from the chronological results
+func selectResults(results []model.ScriptResult, q Query) []model.ScriptResult {
+	selected := make([]model.ScriptResult, 0, len(results))
+	for _, r := range results {
+		if (!q.From.IsZero() && r.StartedAt.Before(q.From)) || (!q.To.IsZero() && r.StartedAt.After(q.To)) {
+			continue
+		}
+		selected = append(selected, r)
+	}
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[len(selected)-q.Limit:]
+	}
+	return selected
+}
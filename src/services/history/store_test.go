@@ -0,0 +1,144 @@
+package history
+
+import (
+	"boogieman/src/model"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func result(startedAt time.Time, counter uint) (r model.ScriptResult) {
+	r.StartedAt = startedAt
+	r.RunCounter = counter
+	r.Success = counter%2 == 0
+	r.Tasks = []model.TaskResult{{Name: "task"}}
+	return
+}
+
+func counters(results []model.ScriptResult) (c []uint) {
+	for _, r := range results {
+		c = append(c, r.RunCounter)
+	}
+	return
+}
+
+func equal(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_Query(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	m := NewMemory(Retention{MaxRecords: 5})
+	for i := uint(1); i <= 7; i++ {
+		_ = m.Add("a", result(start.Add(time.Duration(i)*time.Minute), i))
+	}
+	_ = m.Add("b", result(start, 100))
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []uint
+	}{
+		{"ring keeps the last records", Query{Job: "a"}, []uint{3, 4, 5, 6, 7}},
+		{"limit", Query{Job: "a", Limit: 2}, []uint{6, 7}},
+		{"from", Query{Job: "a", From: start.Add(5 * time.Minute)}, []uint{5, 6, 7}},
+		{"to", Query{Job: "a", To: start.Add(4 * time.Minute)}, []uint{3, 4}},
+		{"from to limit", Query{Job: "a", From: start.Add(4 * time.Minute), To: start.Add(6 * time.Minute), Limit: 2}, []uint{5, 6}},
+		{"another job", Query{Job: "b"}, []uint{100}},
+		{"unknown job", Query{Job: "c"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Query(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equal(counters(got), tt.want) {
+				t.Errorf("Query() = %v, want %v", counters(got), tt.want)
+			}
+		})
+	}
+}
+
+func Test_MemoryMaxAge(t *testing.T) {
+	m := NewMemory(Retention{MaxAge: time.Hour})
+	_ = m.Add("a", result(time.Now().Add(-2*time.Hour), 1))
+	_ = m.Add("a", result(time.Now().Add(-30*time.Minute), 2))
+	_ = m.Add("a", result(time.Now(), 3))
+	got, _ := m.Query(Query{Job: "a"})
+	if !equal(counters(got), []uint{2, 3}) {
+		t.Errorf("outdated records should be removed, got %v", counters(got))
+	}
+}
+
+func Test_JSONL(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "history.jsonl")
+	s, err := NewJSONL(fileName, Retention{MaxRecords: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := uint(1); i <= 5; i++ {
+		if err = s.Add("a", result(start.Add(time.Duration(i)*time.Second), i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a broken line written on a crash is skipped
+	f, _ := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0o644)
+	_, _ = f.WriteString(`{"job": "a", "res`)
+	_ = f.Close()
+
+	s, err = NewJSONL(fileName, Retention{MaxRecords: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	got, _ := s.Query(Query{Job: "a"})
+	if !equal(counters(got), []uint{3, 4, 5}) {
+		t.Fatalf("records should be restored, got %v", counters(got))
+	}
+	if len(got[0].Tasks) != 1 || got[0].Tasks[0].Name != "task" || !got[1].Success {
+		t.Errorf("task results should be restored, got %+v", got[0])
+	}
+	if s.lines != 3 {
+		t.Errorf("the file should be compacted on start, got %v lines", s.lines)
+	}
+}
+
+func Test_New(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{"memory", Options{Store: StoreMemory, MaxRecords: 10, MaxAge: "24h"}, false},
+		{"jsonl", Options{Store: StoreJSONL, File: filepath.Join(t.TempDir(), "h.jsonl")}, false},
+		{"jsonl without file", Options{Store: StoreJSONL}, true},
+		{"wrong max age", Options{MaxAge: "day"}, true},
+		{"unknown store", Options{Store: "sql"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if s != nil {
+				_ = s.Close()
+			}
+		})
+	}
+}
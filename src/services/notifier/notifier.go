@@ -0,0 +1,358 @@
+package notifier
+
+import (
+	"boogieman/src/model"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = model.DefaultLogger
+
+const (
+	TypeWebhook = "webhook"
+	TypeSMTP    = "smtp"
+
+	StateFailure  = "failure"
+	StateRecovery = "recovery"
+
+	queueSize = 100
+)
+
+// Options is the configuration of a notifier
+type Options struct {
+	Name       string
+	Type       string   // webhook or smtp
+	Jobs       []string // notify about these jobs only, all jobs if empty
+	Tasks      bool     // notify about task state changes as well as script ones
+	Failures   uint     // consecutive failures to notify about a failure
+	Retries    uint     // additional delivery attempts
+	RetryDelay time.Duration
+	Timeout    time.Duration // delivery attempt timeout
+	Digest     time.Duration // events are sent in one message per interval if set
+	// webhook
+	URL     string
+	Method  string
+	Headers map[string]string
+	Body    string // text/template of the request body or the email with Event data
+	// smtp
+	Host          string
+	Port          int    // 587 for starttls, 465 for implicit and 25 for none by default
+	TLS           string // starttls, implicit or none
+	TLSSkipVerify bool
+	Username      string
+	Password      string
+	From          string
+	To            []string            // recipients of all jobs
+	JobRecipients map[string][]string // additional recipients by job name
+	Subject       string              // text/template with Event data
+	DigestSubject string              // text/template with Digest data
+	DigestBody    string              // text/template with Digest data
+}
+
+// Event is a state change of a job script or a task
+type Event struct {
+	Job      string    `json:"job"`
+	Task     string    `json:"task,omitempty"` // empty for the script state
+	State    string    `json:"state"`          // failure or recovery
+	Status   string    `json:"status"`         // finished or timeout
+	Failures uint      `json:"failures"`       // consecutive failures
+	Time     time.Time `json:"time"`
+	Result   any       `json:"result"` // model.ScriptResult or model.TaskResult
+}
+
+// Digest is the events happened within the digest interval
+type Digest struct {
+	Events []Event
+}
+
+// Sender delivers the event
+type Sender interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// DigestSender delivers the events in one message
+type DigestSender interface {
+	// SendDigest returns DigestError if the digest is delivered partially
+	SendDigest(ctx context.Context, d Digest) error
+}
+
+// DigestError is the error of the partially delivered digest, only the undelivered events are sent again
+type DigestError struct {
+	Undelivered Digest
+	Err         error
+}
+
+func (e *DigestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DigestError) Unwrap() error {
+	return e.Err
+}
+
+// state is the notified state of a script or a task
+type state struct {
+	failures uint
+	notified bool // the failure is notified
+}
+
+// Notifier sends the events about job state changes, the events are delivered one by one in the background
+type Notifier struct {
+	Options
+	sender Sender
+	jobs   map[string]bool
+	states map[string]*state
+	queue  chan Event
+	done   chan struct{}
+	stop   context.Context
+	cancel context.CancelFunc
+	logger model.Logger
+	closed bool
+	sync.Mutex
+}
+
+func NewNotifier(o Options, sender Sender) *Notifier {
+	n := &Notifier{
+		Options: o,
+		sender:  sender,
+		states:  make(map[string]*state),
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+		logger:  model.NewChainLogger(model.NewChainLogger(logger, "notifier"), o.Name),
+	}
+	if len(o.Jobs) > 0 {
+		n.jobs = make(map[string]bool, len(o.Jobs))
+		for _, j := range o.Jobs {
+			n.jobs[j] = true
+		}
+	}
+	n.stop, n.cancel = context.WithCancel(context.Background())
+	go n.deliver()
+	return n
+}
+
+// Notify compares the job result with the previous one and sends events about the state changes
+func (n *Notifier) Notify(job string, prev, cur model.ScriptResult) {
+	if n.jobs != nil && !n.jobs[job] {
+		return
+	}
+	if cur.RunCounter == prev.RunCounter || cur.Status == string(model.EStatusSkipped) {
+		return
+	}
+	n.change(job, "", prev.Result, cur.Result, cur.Status, cur)
+	if !n.Tasks {
+		return
+	}
+	prevTasks := make(map[string]model.TaskResult, len(prev.Tasks))
+	for _, t := range prev.Tasks {
+		prevTasks[t.Name] = t
+	}
+	for _, t := range cur.Tasks {
+		if t.Status == string(model.EStatusSkipped) {
+			continue
+		}
+		n.change(job, t.Name, prevTasks[t.Name].Result, t.Result, t.Status, t)
+	}
+}
+
+// change updates the state of the script or the task and queues the event if the state is changed,
+// the state is created from the previous result if it's unknown
+func (n *Notifier) change(job, task string, prev, cur model.Result, status string, result any) {
+	n.Lock()
+	defer n.Unlock()
+	key := job + "/" + task
+	s, ok := n.states[key]
+	if !ok {
+		s = &state{}
+		if prev.RunCounter > 0 && !prev.Success {
+			s.failures = 1
+		}
+		n.states[key] = s
+	}
+	var e *Event
+	if cur.Success {
+		if s.notified {
+			e = &Event{State: StateRecovery}
+		}
+		s.failures, s.notified = 0, false
+	} else {
+		s.failures++
+		if !s.notified && s.failures >= n.Failures {
+			e = &Event{State: StateFailure}
+			s.notified = true
+		}
+	}
+	if e == nil || n.closed {
+		return
+	}
+
+	e.Job, e.Task, e.Status, e.Failures, e.Time, e.Result = job, task, status, s.failures, time.Now(), result
+	select {
+	case n.queue <- *e:
+	default:
+		n.logger.Printf("the queue is full, the %v event of %v is dropped\n", e.State, key)
+	}
+}
+
+// Forget drops the notified states of the job script and its tasks, e.g. when the job is removed or replaced
+func (n *Notifier) Forget(job string) {
+	n.Lock()
+	defer n.Unlock()
+	for key := range n.states {
+		if strings.HasPrefix(key, job+"/") {
+			delete(n.states, key)
+		}
+	}
+}
+
+func (n *Notifier) deliver() {
+	defer close(n.done)
+	if ds, ok := n.sender.(DigestSender); ok && n.Digest > 0 {
+		n.deliverDigest(ds)
+		return
+	}
+	for e := range n.queue {
+		err := n.send(func(ctx context.Context) error {
+			return n.sender.Send(ctx, e)
+		})
+		if err != nil {
+			n.logger.Printf("can't deliver the %v event of %v/%v: %v\n", e.State, e.Job, e.Task, err)
+		}
+	}
+}
+
+// deliverDigest collects the events and delivers them once per the digest interval
+func (n *Notifier) deliverDigest(ds DigestSender) {
+	var d Digest
+	flush := func() {
+		if len(d.Events) == 0 {
+			return
+		}
+		digest := d
+		d = Digest{}
+		err := n.send(func(ctx context.Context) error {
+			err := ds.SendDigest(ctx, digest)
+			var de *DigestError
+			if errors.As(err, &de) {
+				digest = de.Undelivered
+			}
+			return err
+		})
+		if err != nil {
+			n.logger.Printf("can't deliver the digest of %v events: %v\n", len(digest.Events), err)
+		}
+	}
+	ticker := time.NewTicker(n.Digest)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-n.queue:
+			if !ok {
+				flush()
+				return
+			}
+			d.Events = append(d.Events, e)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send delivers the message with retries
+func (n *Notifier) send(deliver func(ctx context.Context) error) (err error) {
+	for attempt := uint(0); attempt <= n.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(n.RetryDelay):
+			case <-n.stop.Done():
+				return fmt.Errorf("%w, shutdown", err)
+			}
+		}
+		ctx, cancel := context.WithTimeout(n.stop, n.Timeout)
+		err = deliver(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
+// Shutdown delivers the queued events until the context is done
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	n.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		<-n.done
+		return ctx.Err()
+	}
+}
+
+// Notifiers is the list of the configured notifiers
+type Notifiers []*Notifier
+
+// New creates the notifiers from the configuration
+func New(options []Options) (notifiers Notifiers, err error) {
+	names := make(map[string]bool, len(options))
+	for _, o := range options {
+		if o.Name == "" {
+			return nil, errors.New("notifier name should be defined")
+		}
+		if names[o.Name] {
+			return nil, fmt.Errorf("notifier name %v isn't unique", o.Name)
+		}
+		names[o.Name] = true
+
+		var sender Sender
+		switch o.Type {
+		case TypeWebhook:
+			sender, err = NewWebhook(o)
+		case TypeSMTP:
+			sender, err = NewSMTP(o)
+		default:
+			err = fmt.Errorf("unknown type %v", o.Type)
+		}
+		if _, ok := sender.(DigestSender); err == nil && o.Digest > 0 && !ok {
+			err = fmt.Errorf("digest isn't supported by %v", o.Type)
+		}
+		if err != nil {
+			_ = notifiers.Shutdown(context.Background())
+			return nil, fmt.Errorf("[%v] %w", o.Name, err)
+		}
+		notifiers = append(notifiers, NewNotifier(o, sender))
+	}
+	return
+}
+
+func (n Notifiers) Notify(job string, prev, cur model.ScriptResult) {
+	for _, notifier := range n {
+		notifier.Notify(job, prev, cur)
+	}
+}
+
+func (n Notifiers) Forget(job string) {
+	for _, notifier := range n {
+		notifier.Forget(job)
+	}
+}
+
+func (n Notifiers) Shutdown(ctx context.Context) (err error) {
+	for _, notifier := range n {
+		if e := notifier.Shutdown(ctx); e != nil {
+			err = e
+		}
+	}
+	return
+}
@@ -0,0 +1,275 @@
+package notifier
+
+import (
+	"boogieman/src/configuration"
+	"boogieman/src/model"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// webhookServer records the request bodies and fails the first requests
+type webhookServer struct {
+	*httptest.Server
+	failFirst int
+	requests  []*http.Request
+	bodies    []string
+	sync.Mutex
+}
+
+func newWebhookServer(failFirst int) *webhookServer {
+	s := &webhookServer{failFirst: failFirst}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.Lock()
+		defer s.Unlock()
+		if s.failFirst > 0 {
+			s.failFirst--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+	}))
+	return s
+}
+
+func (s *webhookServer) received() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+// options parses the notifier section of the daemon configuration
+func options(t *testing.T, config string) Options {
+	var o configuration.NotifierOptions
+	if err := yaml.Unmarshal([]byte(config), &o); err != nil {
+		t.Fatal(err)
+	}
+	return Options(o)
+}
+
+func scriptResult(counter uint, success bool, tasks ...bool) (r model.ScriptResult) {
+	r.RunCounter = counter
+	r.Success = success
+	r.Status = string(model.EStatusFinished)
+	for i, taskSuccess := range tasks {
+		var t model.TaskResult
+		t.Name = []string{"a", "b"}[i]
+		t.Status = string(model.EStatusFinished)
+		t.RunCounter = counter
+		t.Success = taskSuccess
+		r.Tasks = append(r.Tasks, t)
+	}
+	return
+}
+
+func events(t *testing.T, bodies []string) (e []string) {
+	for _, b := range bodies {
+		var event Event
+		if err := json.Unmarshal([]byte(b), &event); err != nil {
+			t.Fatalf("wrong event %v: %v", b, err)
+		}
+		e = append(e, event.Job+"/"+event.Task+" "+event.State)
+	}
+	return
+}
+
+// run notifies about the sequence of results and waits for the delivery of the events
+func run(t *testing.T, n *Notifier, job string, results ...model.ScriptResult) {
+	prev := model.ScriptResult{}
+	for _, r := range results {
+		n.Notify(job, prev, r)
+		prev = r
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_Notify(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		job     string
+		results []model.ScriptResult
+		want    []string
+	}{
+		{
+			name:    "script and task transitions",
+			config:  "name: hook",
+			job:     "j",
+			results: []model.ScriptResult{scriptResult(1, true, true, true), scriptResult(2, false, false, true), scriptResult(3, false, false, true), scriptResult(4, true, true, true)},
+			want:    []string{"j/ failure", "j/a failure", "j/ recovery", "j/a recovery"},
+		},
+		{
+			name:    "first run failure",
+			config:  "name: hook\ntasks: false",
+			job:     "j",
+			results: []model.ScriptResult{scriptResult(1, false, false)},
+			want:    []string{"j/ failure"},
+		},
+		{
+			name:    "debounce consecutive failures",
+			config:  "name: hook\ntasks: false\nfailures: 3",
+			job:     "j",
+			results: []model.ScriptResult{scriptResult(1, false), scriptResult(2, false), scriptResult(3, true), scriptResult(4, false), scriptResult(5, false), scriptResult(6, false), scriptResult(7, false), scriptResult(8, true)},
+			want:    []string{"j/ failure", "j/ recovery"},
+		},
+		{
+			name:    "the same run isn't notified twice",
+			config:  "name: hook\ntasks: false",
+			job:     "j",
+			results: []model.ScriptResult{scriptResult(1, false), scriptResult(1, false)},
+			want:    []string{"j/ failure"},
+		},
+		{
+			name:    "other jobs are filtered",
+			config:  "name: hook\njobs: [other]",
+			job:     "j",
+			results: []model.ScriptResult{scriptResult(1, false, false)},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newWebhookServer(0)
+			defer srv.Close()
+			o := options(t, tt.config+"\nurl: "+srv.URL)
+			w, err := NewWebhook(o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run(t, NewNotifier(o, w), tt.job, tt.results...)
+			if got := events(t, srv.received()); !equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_NotifyForget(t *testing.T) {
+	srv := newWebhookServer(0)
+	defer srv.Close()
+	o := options(t, "name: hook\ntasks: false\nfailures: 2\nurl: "+srv.URL)
+	w, err := NewWebhook(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNotifier(o, w)
+	for _, job := range []string{"j", "jj"} {
+		n.Notify(job, model.ScriptResult{}, scriptResult(1, false))
+	}
+	// the failures of the replaced job aren't counted for the new one
+	n.Forget("j")
+	for _, job := range []string{"j", "jj"} {
+		n.Notify(job, model.ScriptResult{}, scriptResult(1, false))
+	}
+	run(t, n, "j")
+	if got, want := events(t, srv.received()), []string{"jj/ failure"}; !equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func Test_WebhookDelivery(t *testing.T) {
+	srv := newWebhookServer(2)
+	defer srv.Close()
+	o := options(t, `
+name: hook
+url: `+srv.URL+`/hook
+method: PUT
+retries: 2
+retry_delay: 10
+headers:
+  Authorization: Bearer secret
+body: '{"text": "{{ .Job }} is {{ .State }} after {{ .Failures }} failures", "runs": {{ json .Result.RunCounter }}}'
+tasks: false
+`)
+	w, err := NewWebhook(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, NewNotifier(o, w), "j", scriptResult(7, false))
+
+	got := srv.received()
+	if len(got) != 1 {
+		t.Fatalf("the event should be delivered after retries, got %v", got)
+	}
+	if want := `{"text": "j is failure after 1 failures", "runs": 7}`; got[0] != want {
+		t.Errorf("body = %v, want %v", got[0], want)
+	}
+	r := srv.requests[0]
+	if r.Method != http.MethodPut || r.URL.Path != "/hook" || r.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("wrong request %v %v %v", r.Method, r.URL, r.Header)
+	}
+}
+
+func Test_WebhookRetriesExceeded(t *testing.T) {
+	srv := newWebhookServer(3)
+	defer srv.Close()
+	o := options(t, "name: hook\nretries: 2\nretry_delay: 10\nurl: "+srv.URL)
+	w, _ := NewWebhook(o)
+	run(t, NewNotifier(o, w), "j", scriptResult(1, false))
+	if got := srv.received(); len(got) != 0 {
+		t.Errorf("the event shouldn't be delivered, got %v", got)
+	}
+}
+
+func Test_New(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"valid", "[{name: a, url: 'http://localhost/a'}, {name: b, url: 'http://localhost/b'}]", false},
+		{"without name", "[{url: 'http://localhost/a'}]", true},
+		{"duplicate name", "[{name: a, url: 'http://localhost/a'}, {name: a, url: 'http://localhost/b'}]", true},
+		{"without url", "[{name: a}]", true},
+		{"unknown type", "[{name: a, type: pager}]", true},
+		{"wrong template", "[{name: a, url: 'http://localhost/a', body: '{{ .Job '}]", true},
+		{"webhook digest", "[{name: a, url: 'http://localhost/a', digest: 60000}]", true},
+		{"smtp", "[{name: a, type: smtp, host: localhost, from: a@localhost, to: [b@localhost], digest: 60000}]", false},
+		{"smtp without recipients", "[{name: a, type: smtp, host: localhost, from: a@localhost}]", true},
+		{"smtp wrong tls", "[{name: a, type: smtp, host: localhost, from: a@localhost, to: [b@localhost], tls: ssl}]", true},
+		{"smtp auth without tls", "[{name: a, type: smtp, host: localhost, from: a@localhost, to: [b@localhost], tls: none, username: a}]", false},
+		{"smtp remote auth without tls", "[{name: a, type: smtp, host: mail.example.com, from: a@localhost, to: [b@localhost], tls: none, username: a}]", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o []configuration.NotifierOptions
+			if err := yaml.Unmarshal([]byte(tt.config), &o); err != nil {
+				t.Fatal(err)
+			}
+			options := make([]Options, 0, len(o))
+			for _, c := range o {
+				options = append(options, Options(c))
+			}
+			n, err := New(options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			_ = n.Shutdown(context.Background())
+		})
+	}
+}
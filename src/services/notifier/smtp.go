@@ -0,0 +1,255 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "implicit"
+	TLSNone     = "none"
+)
+
+const (
+	defaultSubject = `[boogieman] {{ .Job }}{{ if .Task }}/{{ .Task }}{{ end }} {{ .State }}`
+	defaultBody    = `Job: {{ .Job }}
+{{ if .Task }}Task: {{ .Task }}
+{{ end }}State: {{ .State }}
+Status: {{ .Status }}
+Consecutive failures: {{ .Failures }}
+Time: {{ .Time.Format "2006-01-02 15:04:05 MST" }}
+
+{{ json .Result }}
+`
+	defaultDigestSubject = `[boogieman] {{ len .Events }} state changes`
+	defaultDigestBody    = `{{ range .Events }}{{ .Time.Format "2006-01-02 15:04:05 MST" }} {{ .Job }}{{ if .Task }}/{{ .Task }}{{ end }} {{ .State }} ({{ .Status }}, {{ .Failures }} failures)
+{{ end }}`
+)
+
+// SMTP sends the event as an email
+type SMTP struct {
+	addr          string
+	host          string
+	tls           string
+	tlsConfig     *tls.Config
+	username      string
+	password      string
+	from          string
+	to            []string
+	jobRecipients map[string][]string
+	subject       *template.Template
+	body          *template.Template
+	digestSubject *template.Template
+	digestBody    *template.Template
+}
+
+func NewSMTP(o Options) (m *SMTP, err error) {
+	if o.Host == "" {
+		return nil, errors.New("host should be defined")
+	}
+	if o.From == "" {
+		return nil, errors.New("from should be defined")
+	}
+	if len(o.To) == 0 && len(o.JobRecipients) == 0 {
+		return nil, errors.New("either to or job_recipients should be defined")
+	}
+	port := o.Port
+	switch o.TLS {
+	case TLSStartTLS:
+		port = defaultInt(port, 587)
+	case TLSImplicit:
+		port = defaultInt(port, 465)
+	case TLSNone:
+		port = defaultInt(port, 25)
+		// the credentials would be sent in clear text, smtp.PlainAuth refuses it on sending anyway
+		if o.Username != "" && !isLocalhost(o.Host) {
+			return nil, errors.New("username with tls none is allowed for localhost only")
+		}
+	default:
+		return nil, fmt.Errorf("unknown tls mode %v", o.TLS)
+	}
+	m = &SMTP{
+		addr:          net.JoinHostPort(o.Host, strconv.Itoa(port)),
+		host:          o.Host,
+		tls:           o.TLS,
+		tlsConfig:     &tls.Config{ServerName: o.Host, InsecureSkipVerify: o.TLSSkipVerify, MinVersion: tls.VersionTLS12}, //nolint:gosec
+		username:      o.Username,
+		password:      o.Password,
+		from:          o.From,
+		to:            o.To,
+		jobRecipients: o.JobRecipients,
+	}
+	templates := []struct {
+		t      **template.Template
+		name   string
+		source string
+		def    string
+	}{
+		{&m.subject, "subject", o.Subject, defaultSubject},
+		{&m.body, "body", o.Body, defaultBody},
+		{&m.digestSubject, "digest_subject", o.DigestSubject, defaultDigestSubject},
+		{&m.digestBody, "digest_body", o.DigestBody, defaultDigestBody},
+	}
+	for _, t := range templates {
+		source := t.source
+		if source == "" {
+			source = t.def
+		}
+		if *t.t, err = template.New(t.name).Funcs(templateFuncs).Parse(source); err != nil {
+			return nil, fmt.Errorf("wrong %v template: %w", t.name, err)
+		}
+	}
+	return
+}
+
+// isLocalhost is true for the hosts smtp.PlainAuth accepts without TLS
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func defaultInt(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func (m *SMTP) Send(ctx context.Context, e Event) error {
+	return m.sendMail(ctx, m.recipients(e.Job), m.subject, m.body, e)
+}
+
+// SendDigest sends a message to every set of recipients with the events of their jobs,
+// the events of the failed recipients are returned in DigestError
+func (m *SMTP) SendDigest(ctx context.Context, d Digest) error {
+	digests := make(map[string]*Digest)
+	recipients := make(map[string][]string)
+	var keys []string
+	for _, e := range d.Events {
+		to := m.recipients(e.Job)
+		key := strings.Join(to, ",")
+		if _, ok := digests[key]; !ok {
+			digests[key] = &Digest{}
+			recipients[key] = to
+			keys = append(keys, key)
+		}
+		digests[key].Events = append(digests[key].Events, e)
+	}
+	var (
+		errs        []error
+		undelivered Digest
+	)
+	for _, key := range keys {
+		if err := m.sendMail(ctx, recipients[key], m.digestSubject, m.digestBody, digests[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", key, err))
+			undelivered.Events = append(undelivered.Events, digests[key].Events...)
+		}
+	}
+	if len(errs) > 0 {
+		return &DigestError{Undelivered: undelivered, Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+// recipients returns the sorted recipients of the job
+func (m *SMTP) recipients(job string) []string {
+	set := make(map[string]bool)
+	for _, r := range append(append([]string(nil), m.to...), m.jobRecipients[job]...) {
+		set[r] = true
+	}
+	to := make([]string, 0, len(set))
+	for r := range set {
+		to = append(to, r)
+	}
+	sort.Strings(to)
+	return to
+}
+
+func (m *SMTP) sendMail(ctx context.Context, to []string, subjectTemplate, bodyTemplate *template.Template, data any) (err error) {
+	if len(to) == 0 {
+		return nil
+	}
+	var subject, body bytes.Buffer
+	if err = subjectTemplate.Execute(&subject, data); err != nil {
+		return
+	}
+	if err = bodyTemplate.Execute(&body, data); err != nil {
+		return
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %v\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %v\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.Write(body.Bytes())
+
+	return m.deliver(ctx, to, msg.Bytes())
+}
+
+func (m *SMTP) deliver(ctx context.Context, to []string, msg []byte) (err error) {
+	var conn net.Conn
+	dialer := &net.Dialer{}
+	if m.tls == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: m.tlsConfig}).DialContext(ctx, "tcp", m.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", m.addr)
+	}
+	if err != nil {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	defer c.Close()
+
+	if m.tls == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("the server doesn't support STARTTLS")
+		}
+		if err = c.StartTLS(m.tlsConfig); err != nil {
+			return
+		}
+	}
+	if m.username != "" {
+		if err = c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return
+		}
+	}
+	if err = c.Mail(m.from); err != nil {
+		return
+	}
+	for _, r := range to {
+		if err = c.Rcpt(r); err != nil {
+			return
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return
+	}
+	if _, err = w.Write(msg); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return c.Quit()
+}
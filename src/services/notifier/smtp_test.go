@@ -0,0 +1,336 @@
+package notifier
+
+import (
+	"boogieman/src/model"
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mail is a message received by the smtp server
+type mail struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+	auth string
+}
+
+// smtpServer is a minimal SMTP server with STARTTLS, implicit TLS and PLAIN auth
+type smtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	startTLS  bool
+	reject    map[string]int // the number of times the recipient is rejected
+	mails     []mail
+	sync.Mutex
+}
+
+// newSMTPServer starts the server and returns the client pool trusting its certificate
+func newSMTPServer(t *testing.T, implicitTLS, startTLS bool) (s *smtpServer, roots *x509.CertPool) {
+	// the certificate of httptest is valid for 127.0.0.1
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(ts.Close)
+	roots = ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	s = &smtpServer{tlsConfig: ts.TLS.Clone(), startTLS: startTLS}
+	s.tlsConfig.NextProtos = nil
+	var err error
+	if implicitTLS {
+		s.listener, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	} else {
+		s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.listener.Close() })
+	go func() {
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, implicitTLS)
+		}
+	}()
+	return
+}
+
+func (s *smtpServer) port() string {
+	return fmt.Sprint(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *smtpServer) received() []mail {
+	s.Lock()
+	defer s.Unlock()
+	return append([]mail(nil), s.mails...)
+}
+
+//nolint:funlen
+func (s *smtpServer) serve(conn net.Conn, isTLS bool) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = fmt.Fprint(conn, line+"\r\n") }
+	var m mail
+	m.tls = isTLS
+	reply("220 localhost ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			if s.startTLS && !m.tls {
+				reply("250-localhost")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-localhost")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, r, m.tls = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			m.auth = line
+			reply("235 authenticated")
+		case "MAIL":
+			m.from = line
+			reply("250 ok")
+		case "RCPT":
+			to := strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">")
+			s.Lock()
+			rejected := s.reject[to] > 0
+			if rejected {
+				s.reject[to]--
+			}
+			s.Unlock()
+			if rejected {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			m.to = append(m.to, to)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.data = data.String()
+			s.Lock()
+			s.mails = append(s.mails, m)
+			s.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func smtpNotifier(t *testing.T, config string, roots *x509.CertPool) *Notifier {
+	o := options(t, config)
+	m, err := NewSMTP(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.tlsConfig.RootCAs = roots
+	return NewNotifier(o, m)
+}
+
+func Test_SMTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		startTLS    bool
+		config      string
+		wantTo      []string
+		wantAuth    bool
+		wantErr     bool
+	}{
+		{
+			name:     "starttls with auth and job recipients",
+			startTLS: true,
+			config:   "tls: starttls\nusername: user\npassword: secret\nto: [ops@example.com]\njob_recipients: {j: [dev@example.com, ops@example.com]}",
+			wantTo:   []string{"dev@example.com", "ops@example.com"},
+			wantAuth: true,
+		},
+		{
+			name:        "implicit tls",
+			implicitTLS: true,
+			config:      "tls: implicit\nto: [ops@example.com]",
+			wantTo:      []string{"ops@example.com"},
+		},
+		{
+			name:   "without tls",
+			config: "tls: none\njob_recipients: {j: [dev@example.com]}",
+			wantTo: []string{"dev@example.com"},
+		},
+		{
+			name:    "starttls isn't supported",
+			config:  "tls: starttls\nto: [ops@example.com]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, roots := newSMTPServer(t, tt.implicitTLS, tt.startTLS)
+			n := smtpNotifier(t, "name: mail\ntype: smtp\nhost: 127.0.0.1\nport: "+srv.port()+"\nfrom: boogieman@example.com\nretries: 0\ntasks: false\n"+tt.config, roots)
+			run(t, n, "j", scriptResult(1, false))
+
+			mails := srv.received()
+			if tt.wantErr {
+				if len(mails) != 0 {
+					t.Fatalf("the mail shouldn't be sent, got %+v", mails)
+				}
+				return
+			}
+			if len(mails) != 1 {
+				t.Fatalf("one mail should be sent, got %v", len(mails))
+			}
+			m := mails[0]
+			if strings.Join(m.to, ",") != strings.Join(tt.wantTo, ",") {
+				t.Errorf("recipients = %v, want %v", m.to, tt.wantTo)
+			}
+			if tt.implicitTLS || tt.startTLS {
+				if !m.tls {
+					t.Error("the mail should be sent over TLS")
+				}
+			}
+			if tt.wantAuth != (m.auth != "") {
+				t.Errorf("auth = %q, want auth %v", m.auth, tt.wantAuth)
+			}
+			if !strings.Contains(m.data, "Subject: [boogieman] j failure\r\n") || !strings.Contains(m.data, "State: failure\r\n") {
+				t.Errorf("wrong message %v", m.data)
+			}
+		})
+	}
+}
+
+func Test_SMTPDigest(t *testing.T) {
+	srv, roots := newSMTPServer(t, false, false)
+	n := smtpNotifier(t, `
+name: mail
+type: smtp
+tls: none
+host: 127.0.0.1
+port: `+srv.port()+`
+from: boogieman@example.com
+to: [ops@example.com]
+job_recipients: {k: [dev@example.com]}
+digest: 60000
+digest_subject: '{{ len .Events }} changes'
+`, roots)
+	n.Notify("j", model.ScriptResult{}, scriptResult(1, false, false))
+	n.Notify("k", model.ScriptResult{}, scriptResult(1, false))
+	run(t, n, "j", scriptResult(2, true, true))
+
+	mails := srv.received()
+	if len(mails) != 2 {
+		t.Fatalf("a digest should be sent to every set of recipients, got %v", len(mails))
+	}
+	for _, m := range mails {
+		switch strings.Join(m.to, ",") {
+		case "ops@example.com":
+			if !strings.Contains(m.data, "Subject: 4 changes\r\n") || strings.Count(m.data, " j") != 4 {
+				t.Errorf("wrong digest %v", m.data)
+			}
+		case "dev@example.com,ops@example.com":
+			if !strings.Contains(m.data, "Subject: 1 changes\r\n") || !strings.Contains(m.data, " k failure") {
+				t.Errorf("wrong digest %v", m.data)
+			}
+		default:
+			t.Errorf("unexpected recipients %v", m.to)
+		}
+	}
+}
+
+func Test_SMTPDigestPartialRetry(t *testing.T) {
+	srv, roots := newSMTPServer(t, false, false)
+	srv.reject = map[string]int{"dev@example.com": 1}
+	n := smtpNotifier(t, `
+name: mail
+type: smtp
+tls: none
+host: 127.0.0.1
+port: `+srv.port()+`
+from: boogieman@example.com
+to: [ops@example.com]
+job_recipients: {k: [dev@example.com]}
+digest: 60000
+retry_delay: 10
+`, roots)
+	n.Notify("j", model.ScriptResult{}, scriptResult(1, false))
+	run(t, n, "k", scriptResult(1, false))
+
+	// the digest of the failed recipients is sent again, the delivered one isn't
+	sent := map[string]int{}
+	for _, m := range srv.received() {
+		sent[strings.Join(m.to, ",")]++
+	}
+	want := map[string]int{"ops@example.com": 1, "dev@example.com,ops@example.com": 1}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("digests = %v, want %v", sent, want)
+	}
+}
+
+func Test_SMTPSendDigestError(t *testing.T) {
+	srv, roots := newSMTPServer(t, false, false)
+	srv.reject = map[string]int{"dev@example.com": 1, "qa@example.com": 1}
+	o := options(t, `
+name: mail
+type: smtp
+tls: none
+host: 127.0.0.1
+port: `+srv.port()+`
+from: boogieman@example.com
+to: [ops@example.com]
+job_recipients: {k: [dev@example.com], l: [qa@example.com]}
+`)
+	m, err := NewSMTP(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.tlsConfig.RootCAs = roots
+
+	err = m.SendDigest(context.Background(), Digest{Events: []Event{{Job: "j"}, {Job: "k"}, {Job: "l"}, {Job: "k"}}})
+	var de *DigestError
+	if !errors.As(err, &de) {
+		t.Fatalf("error should be DigestError, got %v", err)
+	}
+	if n := len(de.Undelivered.Events); n != 3 {
+		t.Errorf("undelivered events = %v, want 3", n)
+	}
+	for _, to := range []string{"dev@example.com", "qa@example.com"} {
+		if !strings.Contains(err.Error(), to) {
+			t.Errorf("error %v should contain the failure of %v", err, to)
+		}
+	}
+}
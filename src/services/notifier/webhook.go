@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+)
+
+// Webhook sends the event as an HTTP request
+type Webhook struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template
+	client  *http.Client
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func NewWebhook(o Options) (w *Webhook, err error) {
+	if o.URL == "" {
+		return nil, errors.New("url should be defined")
+	}
+	if _, err = url.ParseRequestURI(o.URL); err != nil {
+		return nil, err
+	}
+	w = &Webhook{url: o.URL, method: o.Method, headers: o.Headers, client: &http.Client{}}
+	if o.Body != "" {
+		if w.body, err = template.New(o.Name).Funcs(templateFuncs).Parse(o.Body); err != nil {
+			return nil, fmt.Errorf("wrong body template: %w", err)
+		}
+	}
+	return
+}
+
+func (w *Webhook) Send(ctx context.Context, e Event) (err error) {
+	var body bytes.Buffer
+	if w.body != nil {
+		err = w.body.Execute(&body, e)
+	} else {
+		err = json.NewEncoder(&body).Encode(e)
+	}
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, &body)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := w.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %v", res.Status)
+	}
+	return
+}
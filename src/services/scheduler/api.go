@@ -0,0 +1,213 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	httpPathPrefixJobsAPI = httpPathPrefixJobs + "/"
+	maxJobRequestSize     = 1 << 20
+)
+
+var (
+	errJobExists   = errors.New("job already exists")
+	errJobNotFound = errors.New("not found")
+)
+
+// Configurator creates jobs from the API requests and persists the changed jobs
+type Configurator interface {
+	// ParseJob validates the job definition and creates the job with its script
+	ParseJob(name string, data []byte) (model.ScheduleJob, error)
+	// SaveJob persists the job or removes it if j is nil
+	SaveJob(name string, j *model.ScheduleJob) error
+}
+
+// SetConfigurator enables the API to manage jobs:
+// POST, PUT, DELETE /jobs/<name> and POST /jobs/<name>/run|pause|resume,
+// the requests should have the "Authorization: Bearer <token>" header, the API isn't enabled without the token
+func (s *Scheduler) SetConfigurator(c Configurator, token string) error {
+	if token == "" {
+		return errors.New("jobs API requires a token")
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.configurator = c
+	s.apiToken = token
+	s.urlPatterns[httpPathPrefixJobsAPI] = s.httpJobsAPI
+	return nil
+}
+
+// authorized returns true if the request has the bearer token of the API
+func (s *Scheduler) authorized(req *http.Request) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.apiToken)) == 1
+}
+
+func (s *Scheduler) httpJobsAPI(req *http.Request) (code int, jsonData []byte) {
+	if !s.authorized(req) {
+		return httpError(http.StatusUnauthorized, errors.New("wrong or missing bearer token"))
+	}
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, httpPathPrefixJobsAPI), "/")
+	name, action := parts[0], ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+	if name == "" || len(parts) > 2 {
+		return httpError(http.StatusNotFound, errors.New("should be /jobs/<name>[/run|pause|resume]"))
+	}
+
+	switch {
+	case action == "" && req.Method == http.MethodGet:
+		j, err := s.getJob(name)
+		if err != nil {
+			return httpError(http.StatusNotFound, err)
+		}
+		return s.jobResponse(http.StatusOK, j)
+	case action == "" && (req.Method == http.MethodPost || req.Method == http.MethodPut):
+		return s.apiPutJob(req, name, req.Method == http.MethodPut)
+	case action == "" && req.Method == http.MethodDelete:
+		return s.apiDeleteJob(name)
+	case action == "run" && req.Method == http.MethodPost:
+		return s.apiRunJob(name)
+	case (action == "pause" || action == "resume") && req.Method == http.MethodPost:
+		return s.apiPauseJob(name, action == "pause")
+	case action == "" || action == "run" || action == "pause" || action == "resume":
+		return httpError(http.StatusMethodNotAllowed, fmt.Errorf("method %v isn't allowed", req.Method))
+	}
+	return httpError(http.StatusNotFound, fmt.Errorf("unknown action %v", action))
+}
+
+// apiPutJob creates the job or replaces it if replace is true
+func (s *Scheduler) apiPutJob(req *http.Request, name string, replace bool) (code int, jsonData []byte) {
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxJobRequestSize))
+	if err != nil {
+		return httpError(http.StatusBadRequest, err)
+	}
+	j, err := s.configurator.ParseJob(name, data)
+	if err != nil {
+		return httpError(http.StatusBadRequest, err)
+	}
+
+	return s.applyJobs(name, func(jobs []model.ScheduleJob) ([]model.ScheduleJob, int, error) {
+		idx := jobIndex(jobs, name)
+		switch {
+		case idx >= 0 && !replace:
+			return nil, http.StatusConflict, errJobExists
+		case idx >= 0:
+			jobs[idx] = j
+			return jobs, http.StatusOK, nil
+		}
+		return append(jobs, j), http.StatusCreated, nil
+	})
+}
+
+func (s *Scheduler) apiDeleteJob(name string) (code int, jsonData []byte) {
+	return s.applyJobs(name, func(jobs []model.ScheduleJob) ([]model.ScheduleJob, int, error) {
+		idx := jobIndex(jobs, name)
+		if idx < 0 {
+			return nil, http.StatusNotFound, errJobNotFound
+		}
+		return append(jobs[:idx], jobs[idx+1:]...), http.StatusOK, nil
+	})
+}
+
+// apiPauseJob removes the job from the schedule or returns it back, the job keeps its results
+func (s *Scheduler) apiPauseJob(name string, pause bool) (code int, jsonData []byte) {
+	return s.applyJobs(name, func(jobs []model.ScheduleJob) ([]model.ScheduleJob, int, error) {
+		idx := jobIndex(jobs, name)
+		if idx < 0 {
+			return nil, http.StatusNotFound, errJobNotFound
+		}
+		jobs[idx].Paused = pause
+		jobs[idx].CronJob = nil
+		return jobs, http.StatusOK, nil
+	})
+}
+
+// apiRunJob starts the job script immediately out of the schedule
+func (s *Scheduler) apiRunJob(name string) (code int, jsonData []byte) {
+	j, err := s.getJob(name)
+	if err != nil {
+		return httpError(http.StatusNotFound, err)
+	}
+	if j.Script.Result().Status == string(model.EStatusRunning) {
+		return httpError(http.StatusConflict, errors.New("job is running"))
+	}
+	go s.runScript(context.Background(), j)
+	return s.jobResponse(http.StatusAccepted, j)
+}
+
+// applyJobs applies the jobs changed by the update function and persists the changed job,
+// update returns the response code, the response is the job or {"error": "..."}
+func (s *Scheduler) applyJobs(
+	name string, update func(jobs []model.ScheduleJob) ([]model.ScheduleJob, int, error),
+) (code int, jsonData []byte) {
+	var updateErr error
+	err := s.updateJobs(func(jobs []model.ScheduleJob) (updated []model.ScheduleJob, err error) {
+		updated, code, updateErr = update(jobs)
+		return updated, updateErr
+	})
+	switch {
+	case updateErr != nil:
+		return httpError(code, updateErr)
+	case err != nil:
+		return httpError(http.StatusBadRequest, err)
+	}
+
+	// the current job is saved, so the configuration file gets the last change of the concurrent requests
+	s.apiLock.Lock()
+	defer s.apiLock.Unlock()
+	var saved *model.ScheduleJob
+	j, err := s.getJob(name)
+	if err == nil {
+		saved = &j
+	}
+	if err = s.configurator.SaveJob(name, saved); err != nil {
+		s.logger.Printf("jobs API: can't save job %v: %v\n", name, err)
+		return httpError(http.StatusInternalServerError, fmt.Errorf("the change is applied but isn't saved: %w", err))
+	}
+	if saved == nil {
+		return code, []byte("{}")
+	}
+	return s.jobResponse(code, j)
+}
+
+func (s *Scheduler) jobResponse(code int, j model.ScheduleJob) (int, []byte) {
+	if j.CronJob != nil {
+		j.NextStartAt = j.CronJob.NextRun()
+	}
+	jsonData, err := json.Marshal(j)
+	if err != nil {
+		s.logger.Printf("jobs API: can't create json response: %v\n", err)
+		return http.StatusInternalServerError, nil
+	}
+	return code, jsonData
+}
+
+func (s *Scheduler) jobsList() []model.ScheduleJob {
+	s.Lock()
+	defer s.Unlock()
+	return append([]model.ScheduleJob(nil), s.jobs...)
+}
+
+func jobIndex(jobs []model.ScheduleJob, name string) int {
+	for i, j := range jobs {
+		if j.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func httpError(code int, err error) (int, []byte) {
+	jsonData, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return code, jsonData
+}
@@ -0,0 +1,294 @@
+package scheduler
+
+import (
+	"boogieman/src/configuration"
+	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-co-op/gocron"
+)
+
+const testAPIToken = "secret"
+
+// apiRequest returns the jobs API request with the test token
+func apiRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	return req
+}
+
+type testConfigurator struct {
+	saved []string
+}
+
+func (c *testConfigurator) ParseJob(name string, data []byte) (j model.ScheduleJob, err error) {
+	if err = json.Unmarshal(data, &j); err != nil {
+		return
+	}
+	if j.Schedule == "" {
+		return j, errors.New("schedule should be defined")
+	}
+	j.Name = name
+	j.Script = &model.Script{}
+	j.ScriptHash = string(data)
+	return
+}
+
+func (c *testConfigurator) SaveJob(name string, _ *model.ScheduleJob) error {
+	c.saved = append(c.saved, name)
+	return nil
+}
+
+//nolint:funlen
+func Test_JobsAPI(t *testing.T) {
+	s := &Scheduler{
+		Scheduler:   gocron.NewScheduler(time.Local),
+		urlPatterns: make(map[string]httpHandler),
+		logger:      model.NewChainLogger(logger, "scheduler"),
+		history:     history.NewMemory(history.Retention{}),
+	}
+	s.urlPatterns[httpPathPrefixJobHistory] = s.httpJobHistory
+	c := &testConfigurator{}
+	if err := s.SetConfigurator(c, testAPIToken); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		jobs     int
+		cronJobs int
+		saved    int
+	}{
+		{"create", http.MethodPost, "/jobs/a", `{"schedule": "60s"}`, http.StatusCreated, 1, 1, 1},
+		{"create existing", http.MethodPost, "/jobs/a", `{"schedule": "60s"}`, http.StatusConflict, 1, 1, 1},
+		{"create invalid", http.MethodPost, "/jobs/b", `{"schedule": ""}`, http.StatusBadRequest, 1, 1, 1},
+		{"create wrong schedule", http.MethodPost, "/jobs/b", `{"schedule": "wrong"}`, http.StatusBadRequest, 1, 1, 1},
+		{"upsert new", http.MethodPut, "/jobs/b", `{"schedule": "60s"}`, http.StatusCreated, 2, 2, 2},
+		{"replace", http.MethodPut, "/jobs/b", `{"schedule": "30s"}`, http.StatusOK, 2, 2, 3},
+		{"get", http.MethodGet, "/jobs/b", "", http.StatusOK, 2, 2, 3},
+		{"pause", http.MethodPost, "/jobs/b/pause", "", http.StatusOK, 2, 1, 4},
+		{"run paused", http.MethodPost, "/jobs/b/run", "", http.StatusAccepted, 2, 1, 4},
+		{"resume", http.MethodPost, "/jobs/b/resume", "", http.StatusOK, 2, 2, 5},
+		{"wrong method", http.MethodGet, "/jobs/b/run", "", http.StatusMethodNotAllowed, 2, 2, 5},
+		{"wrong action", http.MethodPost, "/jobs/b/stop", "", http.StatusNotFound, 2, 2, 5},
+		{"delete", http.MethodDelete, "/jobs/a", "", http.StatusOK, 1, 1, 6},
+		{"delete missing", http.MethodDelete, "/jobs/a", "", http.StatusNotFound, 1, 1, 6},
+		{"run missing", http.MethodPost, "/jobs/a/run", "", http.StatusNotFound, 1, 1, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, apiRequest(tt.method, tt.path, tt.body))
+			if rec.Code != tt.wantCode {
+				t.Errorf("code = %v, want %v, body %v", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if len(s.jobs) != tt.jobs {
+				t.Errorf("jobs = %v, want %v", len(s.jobs), tt.jobs)
+			}
+			if n := len(s.Scheduler.Jobs()); n != tt.cronJobs {
+				t.Errorf("cron jobs = %v, want %v", n, tt.cronJobs)
+			}
+			if len(c.saved) != tt.saved {
+				t.Errorf("saves = %v, want %v", len(c.saved), tt.saved)
+			}
+		})
+	}
+
+	j, _ := s.getJob("b")
+	if j.Schedule != "30s" || j.Paused {
+		t.Errorf("job b should be resumed with the replaced schedule, got %+v", j)
+	}
+	for i := 0; i < 100 && j.Script.ResultFinished().RunCounter == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if j.Script.ResultFinished().RunCounter != 1 {
+		t.Error("paused job should keep its results")
+	}
+
+	// the result is saved to the history after the run
+	var results []model.ScriptResult
+	rec := httptest.NewRecorder()
+	for i := 0; i < 100 && len(results) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/job/history?name=b&limit=10", nil))
+		_ = json.Unmarshal(rec.Body.Bytes(), &results)
+	}
+	if len(results) != 1 {
+		t.Errorf("the manual run should be in the history, got %v %v", rec.Code, rec.Body.String())
+	}
+}
+
+func Test_JobsAPIScriptPath(t *testing.T) {
+	s := &Scheduler{
+		Scheduler:   gocron.NewScheduler(time.Local),
+		urlPatterns: make(map[string]httpHandler),
+		logger:      model.NewChainLogger(logger, "scheduler"),
+	}
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(configFile, []byte("jobs: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// a valid script outside the directory of the configuration file
+	outside := filepath.Join(t.TempDir(), "x.yml")
+	if err := os.WriteFile(outside, []byte("script: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.SetConfigurator(configuration.NewJobsConfigurator(configFile, configuration.GlobalOptions{JobsAPIPersist: true}), testAPIToken)
+
+	for name, body := range map[string]string{
+		"parent dir":                `{"schedule": "60s", "script": "../x.yml"}`,
+		"absolute path":             `{"schedule": "60s", "script": "/tmp/x"}`,
+		"absolute existing":         `{"schedule": "60s", "script": "` + outside + `"}`,
+		"script with content":       `{"schedule": "60s", "script": "../x.yml", "scriptContent": {"script": []}}`,
+		"other script with content": `{"schedule": "60s", "script": "config.yml", "scriptContent": {"script": []}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, apiRequest(http.MethodPost, "/jobs/a", body))
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "script") {
+				t.Errorf("code = %v, want %v, body %v", rec.Code, http.StatusBadRequest, rec.Body.String())
+			}
+		})
+	}
+	if len(s.jobs) != 0 {
+		t.Errorf("jobs = %v, want 0", len(s.jobs))
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != "jobs: []\n" {
+		t.Errorf("configuration file shouldn't be changed, got %s", data)
+	}
+
+	// the script is found in the directory of the configuration file, not in the working directory
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "scripts", "s.yml")
+	if err := os.WriteFile(script, []byte("script: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, apiRequest(http.MethodPost, "/jobs/s", `{"schedule": "60s", "script": "scripts/s.yml"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("code = %v, want %v, body %v", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if j, _ := s.getJob("s"); j.ScriptFile != script {
+		t.Errorf("script = %v, want %v", j.ScriptFile, script)
+	}
+	if data, _ := os.ReadFile(configFile); !strings.Contains(string(data), "script: "+script) {
+		t.Errorf("configuration file should have the script path, got %s", data)
+	}
+}
+
+func Test_JobsAPIConcurrent(t *testing.T) {
+	s := &Scheduler{
+		Scheduler:   gocron.NewScheduler(time.Local),
+		urlPatterns: make(map[string]httpHandler),
+		logger:      model.NewChainLogger(logger, "scheduler"),
+	}
+	_ = s.SetConfigurator(&testConfigurator{}, testAPIToken)
+
+	// every created job is kept by the concurrent requests and job list updates
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(name string) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, apiRequest(http.MethodPost, "/jobs/"+name, `{"schedule": "60s"}`))
+			if rec.Code != http.StatusCreated {
+				t.Errorf("code = %v, want %v, body %v", rec.Code, http.StatusCreated, rec.Body.String())
+			}
+		}(fmt.Sprintf("job%v", i))
+		go func() {
+			defer wg.Done()
+			_ = s.updateJobs(func(jobs []model.ScheduleJob) ([]model.ScheduleJob, error) { return jobs, nil })
+		}()
+	}
+	wg.Wait()
+	if len(s.jobs) != 20 || len(s.Scheduler.Jobs()) != 20 {
+		t.Errorf("jobs = %v, cron jobs = %v, want 20", len(s.jobs), len(s.Scheduler.Jobs()))
+	}
+}
+
+func Test_JobsAPIAuthorization(t *testing.T) {
+	s := &Scheduler{
+		Scheduler:   gocron.NewScheduler(time.Local),
+		urlPatterns: make(map[string]httpHandler),
+		logger:      model.NewChainLogger(logger, "scheduler"),
+	}
+	if err := s.SetConfigurator(&testConfigurator{}, ""); err == nil {
+		t.Fatal("the API shouldn't be enabled without a token")
+	}
+	if _, ok := s.urlPatterns[httpPathPrefixJobsAPI]; ok {
+		t.Fatal("the API shouldn't be mounted without a token")
+	}
+	_ = s.SetConfigurator(&testConfigurator{}, testAPIToken)
+
+	for name, header := range map[string]string{
+		"no token":    "",
+		"wrong token": "Bearer wrong",
+		"basic auth":  "Basic " + testAPIToken,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/jobs/a", strings.NewReader(`{"schedule": "60s"}`))
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("code = %v, want %v", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+	if len(s.jobs) != 0 {
+		t.Errorf("jobs = %v, want 0", len(s.jobs))
+	}
+}
+
+func Test_JobsAPICommands(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(configFile, []byte("jobs: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmdScript := `"scriptContent": {"script": [{"name": "t", "probe": {"name": "cmd", "configuration": {"cmd": "true"}}}]}`
+	hook := `"onFailure": {"cmd": "true"}, "scriptContent": {"script": []}`
+
+	for _, commands := range []bool{false, true} {
+		s := &Scheduler{
+			Scheduler:   gocron.NewScheduler(time.Local),
+			urlPatterns: make(map[string]httpHandler),
+			logger:      model.NewChainLogger(logger, "scheduler"),
+		}
+		global := configuration.GlobalOptions{JobsAPICommands: commands}
+		_ = s.SetConfigurator(configuration.NewJobsConfigurator(configFile, global), testAPIToken)
+		want := http.StatusBadRequest
+		if commands {
+			want = http.StatusCreated
+		}
+		for name, body := range map[string]string{"cmdprobe": cmdScript, "hook": hook} {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, apiRequest(http.MethodPost, "/jobs/"+name, `{"schedule": "60s", `+body+`}`))
+			if rec.Code != want {
+				t.Errorf("%v with jobs_api_commands %v: code = %v, want %v, body %v", name, commands, rec.Code, want, rec.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// availabilityBuckets is the number of buckets a window is split into, the oldest bucket is partially out of the window
+const availabilityBuckets = 60
+
+// DefaultAvailabilityWindows are used if the windows aren't configured
+var DefaultAvailabilityWindows = []string{"1h", "24h", "30d"}
+
+// AvailabilityWindow is a rolling window the task success ratio is computed over
+type AvailabilityWindow struct {
+	Name     string // the window label value as it's configured
+	Duration time.Duration
+}
+
+// ParseAvailabilityWindows parses Go durations, days are also supported as "30d"
+func ParseAvailabilityWindows(names []string) (windows []AvailabilityWindow, err error) {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		var d time.Duration
+		if days, ok := strings.CutSuffix(name, "d"); ok {
+			var n int
+			if n, err = strconv.Atoi(days); err == nil {
+				d = time.Duration(n) * 24 * time.Hour
+			}
+		} else {
+			d, err = time.ParseDuration(name)
+		}
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("wrong availability window %v", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("availability window %v isn't unique", name)
+		}
+		seen[name] = true
+		windows = append(windows, AvailabilityWindow{Name: name, Duration: d})
+	}
+	return
+}
+
+type availabilityBucket struct {
+	start     time.Time
+	successes uint
+	total     uint
+}
+
+// windowCounter counts the results within the window in the buckets of the same size
+type windowCounter struct {
+	window  time.Duration
+	size    time.Duration
+	buckets []availabilityBucket // the oldest bucket first
+}
+
+func (c *windowCounter) add(at time.Time, success bool) {
+	start := at.Truncate(c.size)
+	i := len(c.buckets) - 1
+	for ; i >= 0 && c.buckets[i].start.After(start); i-- {
+	}
+	if i < 0 || !c.buckets[i].start.Equal(start) {
+		// results are added in chronological order, so a bucket is rarely inserted in the middle
+		c.buckets = append(c.buckets, availabilityBucket{})
+		copy(c.buckets[i+2:], c.buckets[i+1:])
+		i++
+		c.buckets[i] = availabilityBucket{start: start}
+	}
+	c.buckets[i].total++
+	if success {
+		c.buckets[i].successes++
+	}
+	c.prune(at)
+}
+
+// prune removes the buckets out of the window
+func (c *windowCounter) prune(now time.Time) {
+	from := now.Add(-c.window)
+	n := 0
+	for n < len(c.buckets) && !c.buckets[n].start.Add(c.size).After(from) {
+		n++
+	}
+	c.buckets = c.buckets[n:]
+}
+
+// sum returns the number of the successful and all results within the window
+func (c *windowCounter) sum(now time.Time) (successes, total uint) {
+	from := now.Add(-c.window)
+	for _, b := range c.buckets {
+		if b.start.Add(c.size).After(from) {
+			successes += b.successes
+			total += b.total
+		}
+	}
+	return
+}
+
+// availabilityTracker counts the task results of the job within the windows, skipped tasks aren't counted
+type availabilityTracker struct {
+	windows []AvailabilityWindow
+	tasks   map[string][]*windowCounter // counters by task name in the windows order
+	sync.Mutex
+}
+
+func newAvailabilityTracker(windows []AvailabilityWindow) *availabilityTracker {
+	return &availabilityTracker{windows: windows, tasks: make(map[string][]*windowCounter)}
+}
+
+// add counts the task results of the run
+func (t *availabilityTracker) add(r model.ScriptResult) {
+	t.Lock()
+	defer t.Unlock()
+	for _, task := range r.Tasks {
+		if task.Status == string(model.EStatusSkipped) {
+			continue
+		}
+		counters, ok := t.tasks[task.Name]
+		if !ok {
+			for _, w := range t.windows {
+				size := w.Duration / availabilityBuckets
+				if size < time.Second {
+					size = time.Second
+				}
+				counters = append(counters, &windowCounter{window: w.Duration, size: size})
+			}
+			t.tasks[task.Name] = counters
+		}
+		at := task.StartedAt
+		if at.IsZero() {
+			at = r.StartedAt
+		}
+		for _, c := range counters {
+			c.add(at, task.Success)
+		}
+	}
+}
+
+// restore counts the results of the job saved in the history within the longest window
+func (t *availabilityTracker) restore(h history.Store, job string, now time.Time) error {
+	var longest time.Duration
+	for _, w := range t.windows {
+		if w.Duration > longest {
+			longest = w.Duration
+		}
+	}
+	if h == nil || longest == 0 {
+		return nil
+	}
+	results, err := h.Query(history.Query{Job: job, From: now.Add(-longest)})
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		t.add(r)
+	}
+	return nil
+}
+
+// taskAvailability is the success ratio of the task within the window
+type taskAvailability struct {
+	window AvailabilityWindow
+	ratio  float64
+	total  uint
+}
+
+// availability returns the success ratios of the task within the windows having results
+func (t *availabilityTracker) availability(task string, now time.Time) (a []taskAvailability) {
+	if t == nil {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	for i, c := range t.tasks[task] {
+		successes, total := c.sum(now)
+		if total > 0 {
+			a = append(a, taskAvailability{t.windows[i], float64(successes) / float64(total), total})
+		}
+	}
+	return
+}
+
+// errorBudgetRemaining returns the share of the allowed failures left within the longest window,
+// it's negative if the SLO is violated
+func errorBudgetRemaining(a []taskAvailability, slo float64) (remaining float64, ok bool) {
+	var longest *taskAvailability
+	for i := range a {
+		if longest == nil || a[i].window.Duration > longest.window.Duration {
+			longest = &a[i]
+		}
+	}
+	if longest == nil || slo <= 0 || slo >= 1 {
+		return 0, false
+	}
+	return 1 - (1-longest.ratio)/(1-slo), true
+}
+
+// SetAvailabilityWindows sets the windows of the task success ratios, it's applied to the jobs added later
+func (s *Scheduler) SetAvailabilityWindows(windows []AvailabilityWindow) {
+	s.Lock()
+	defer s.Unlock()
+	s.availabilityWindows = windows
+}
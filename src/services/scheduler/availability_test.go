@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_ParseAvailabilityWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []string
+		want    []time.Duration
+		wantErr bool
+	}{
+		{"durations and days", []string{"90m", "24h", "30d"}, []time.Duration{90 * time.Minute, 24 * time.Hour, 30 * 24 * time.Hour}, false},
+		{"wrong duration", []string{"1x"}, nil, true},
+		{"wrong days", []string{"xd"}, nil, true},
+		{"zero", []string{"0s"}, nil, true},
+		{"duplicate", []string{"1h", "1h"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAvailabilityWindows(tt.windows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAvailabilityWindows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i, w := range got {
+				if w.Name != tt.windows[i] || w.Duration != tt.want[i] {
+					t.Errorf("window %v = %+v, want %v", i, w, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_AvailabilityTracker(t *testing.T) {
+	windows, _ := ParseAvailabilityWindows([]string{"1h", "24h"})
+	a := newAvailabilityTracker(windows)
+	now := time.Date(2023, 12, 1, 12, 0, 0, 0, time.UTC)
+	// a failure every 4 runs during 2 days, a run every 10 minutes
+	for i := 2 * 24 * 6; i > 0; i-- {
+		a.add(runResult(now.Add(-time.Duration(i)*10*time.Minute), i%4 != 0))
+	}
+	skipped := runResult(now, false)
+	skipped.Tasks[0].Status = string(model.EStatusSkipped)
+	a.add(skipped)
+
+	got := a.availability("task", now)
+	if len(got) != 2 {
+		t.Fatalf("the ratio should be computed for every window, got %+v", got)
+	}
+	if math.Abs(got[1].ratio-0.75) > 0.01 {
+		t.Errorf("24h window ratio = %v, want about 0.75", got[1].ratio)
+	}
+	if got[0].total > 7 || got[1].total < 144 || got[1].total > 147 {
+		t.Errorf("the results out of the window shouldn't be counted, got %v and %v", got[0].total, got[1].total)
+	}
+	if got := a.availability("task", now.Add(48*time.Hour)); len(got) != 0 {
+		t.Errorf("there are no results within the windows, got %+v", got)
+	}
+	if got := a.availability("other", now); len(got) != 0 {
+		t.Errorf("unknown task shouldn't have the ratio, got %+v", got)
+	}
+}
+
+func Test_ErrorBudgetRemaining(t *testing.T) {
+	a := []taskAvailability{
+		{window: AvailabilityWindow{"1h", time.Hour}, ratio: 0.5},
+		{window: AvailabilityWindow{"30d", 30 * 24 * time.Hour}, ratio: 0.995},
+	}
+	tests := []struct {
+		name   string
+		slo    float64
+		want   float64
+		wantOk bool
+	}{
+		{"half of the budget is spent", 0.99, 0.5, true},
+		{"slo is violated", 0.999, -4, true},
+		{"slo isn't defined", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := errorBudgetRemaining(a, tt.slo)
+			if ok != tt.wantOk || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("errorBudgetRemaining() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_AvailabilityRestore(t *testing.T) {
+	h := history.NewMemory(history.Retention{})
+	now := time.Now()
+	_ = h.Add("job", runResult(now.Add(-2*time.Hour), false))
+	_ = h.Add("job", runResult(now.Add(-30*time.Minute), true))
+	_ = h.Add("job", runResult(now.Add(-10*time.Minute), false))
+	_ = h.Add("other", runResult(now.Add(-10*time.Minute), true))
+
+	s := &Scheduler{logger: model.NewChainLogger(logger, "scheduler")}
+	s.SetHistory(h)
+	s.SetAvailabilityWindows([]AvailabilityWindow{{"1h", time.Hour}, {"1d", 24 * time.Hour}})
+	s.addJob(model.ScheduleJob{Name: "job", Script: &model.Script{}})
+
+	got := s.states["job"].availability.availability("task", now)
+	if len(got) != 2 || got[0].total != 2 || got[0].ratio != 0.5 || got[1].total != 3 {
+		t.Errorf("the ratios should be restored from the history, got %+v", got)
+	}
+}
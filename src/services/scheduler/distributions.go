@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var distributionHelp = map[string]string{
+	pNameTaskRuntimeHistogram: "task runtime distribution",
+	pNameTaskRuntimeSummary:   "task runtime distribution",
+	pNameDataHistogram:        "numeric probe data distribution",
+	pNameDataSummary:          "numeric probe data distribution",
+}
+
+// observer is a histogram or a summary
+type observer interface {
+	prometheus.Metric
+	Observe(float64)
+}
+
+// distributionTracker keeps the histograms and summaries of the job tasks requested by the task metric type,
+// they're updated after every run
+type distributionTracker struct {
+	metrics map[string]observer // by name and labels
+	sync.Mutex
+}
+
+func newDistributionTracker() *distributionTracker {
+	return &distributionTracker{metrics: make(map[string]observer)}
+}
+
+// add observes the task runtime and numeric probe data of the run, skipped tasks aren't observed
+func (d *distributionTracker) add(j model.ScheduleJob, r model.ScriptResult) {
+	d.Lock()
+	defer d.Unlock()
+	for _, t := range r.Tasks {
+		if t.Status == string(model.EStatusSkipped) {
+			continue
+		}
+		var taskMetric model.TaskMetric
+		for _, task := range j.Script.Tasks {
+			if task.Name == t.Name {
+				taskMetric = task.Metric
+				break
+			}
+		}
+		if !taskMetric.IsDistribution() {
+			continue
+		}
+		labels := prometheus.Labels{"job": j.Name, "script": j.ScriptFile, "task": t.Name}
+		for k, v := range taskMetric.Labels.Data() {
+			labels[k] = v
+		}
+		d.observe(pNameTaskRuntimeHistogram, pNameTaskRuntimeSummary, taskMetric, labels, float64(t.RuntimeMs))
+
+		if t.Probe.Data == nil {
+			continue
+		}
+		for _, m := range numericProbeMetrics(t.Probe.Data) {
+			if len(taskMetric.Fields) > 0 && (len(m.labelNames) == 0 || m.labelNames[0] != "field" || !contains(taskMetric.Fields, m.labels[0])) {
+				continue
+			}
+			mapItemLabel(&m, taskMetric.ValueMap)
+			dataLabels := prometheus.Labels{"probe": t.Probe.Name}
+			for k, v := range labels {
+				dataLabels[k] = v
+			}
+			for i, name := range m.labelNames {
+				dataLabels[name] = m.labels[i]
+			}
+			d.observe(pNameDataHistogram, pNameDataSummary, taskMetric, dataLabels, m.value)
+		}
+	}
+}
+
+// observe adds the value to the histogram or the summary with the labels, it's created on the first value
+func (d *distributionTracker) observe(histogramName, summaryName string, taskMetric model.TaskMetric, labels prometheus.Labels, value float64) {
+	name := histogramName
+	if taskMetric.Type == model.MetricTypeSummary {
+		name = summaryName
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k+"="+labels[k])
+	}
+	sort.Strings(keys)
+	key := name + "|" + strings.Join(keys, "|")
+
+	o, ok := d.metrics[key]
+	if !ok {
+		if taskMetric.Type == model.MetricTypeSummary {
+			quantiles := taskMetric.Quantiles
+			if len(quantiles) == 0 {
+				quantiles = model.DefaultMetricQuantiles
+			}
+			objectives := make(map[float64]float64, len(quantiles))
+			for _, q := range quantiles {
+				objectives[q] = (1 - q) / 10
+			}
+			o = prometheus.NewSummary(prometheus.SummaryOpts{
+				Name: name, Help: distributionHelp[name], ConstLabels: labels, Objectives: objectives,
+			})
+		} else {
+			buckets := taskMetric.Buckets
+			if len(buckets) == 0 {
+				buckets = model.DefaultMetricBuckets
+			}
+			o = prometheus.NewHistogram(prometheus.HistogramOpts{
+				Name: name, Help: distributionHelp[name], ConstLabels: labels, Buckets: buckets,
+			})
+		}
+		d.metrics[key] = o
+	}
+	o.Observe(value)
+}
+
+// collect sends the histograms and summaries
+func (d *distributionTracker) collect(ch chan<- prometheus.Metric) {
+	if d == nil {
+		return
+	}
+	d.Lock()
+	defer d.Unlock()
+	for _, o := range d.metrics {
+		ch <- o
+	}
+}
+
+// numericProbeMetrics returns the probe metrics of integer and float values
+func numericProbeMetrics(data any) (metrics []metricData) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if !v.IsValid() {
+		return nil
+	}
+	numeric := func(t reflect.Type) bool {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		return reflectIsInt(t.Kind()) || reflectIsFloat(t.Kind())
+	}
+	var numericFields map[string]bool
+	if v.Kind() == reflect.Struct {
+		numericFields = make(map[string]bool)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if name := exportedFieldName(field); name != "" && numeric(field.Type) {
+				numericFields[name] = true
+			}
+		}
+	} else if !numeric(v.Type()) {
+		return nil
+	}
+	for _, m := range probeMetrics(data) {
+		if numericFields != nil && !numericFields[m.labels[0]] {
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return
+}
+
+// mapItemLabel replaces the item label value with the task metric value map one
+func mapItemLabel(m *metricData, valueMap model.MetricLabelsValueMap) {
+	for i, labelName := range m.labelNames {
+		if labelName != "item" {
+			continue
+		}
+		if val, ok := valueMap[m.labels[i]]; ok {
+			m.labels[i] = val
+		}
+		break
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
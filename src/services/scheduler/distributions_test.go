@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_numericProbeMetrics(t *testing.T) {
+	capture := "x"
+	type structData struct {
+		Timings  map[string]int    `json:"timings"`
+		Regex    map[string]bool   `json:"regex"`
+		Captures map[string]string `json:"captures"`
+		ExitCode int               `json:"exitCode"`
+		Capture  *string           `json:"capture"`
+		Matches  *bool             `json:"matches"`
+	}
+	matches := true
+	tests := []struct {
+		name string
+		data any
+		want []string
+	}{
+		{"struct", structData{map[string]int{"a": 1}, map[string]bool{"a": true}, map[string]string{"a": "b"}, 2, &capture, &matches}, []string{"exitCode", "timings,a"}},
+		{"map", map[string]int{"127.0.0.1": 3}, []string{"127.0.0.1"}},
+		{"string map", map[string]string{"a": "b"}, nil},
+		{"number", 1.5, []string{""}},
+		{"bool", true, nil},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range numericProbeMetrics(tt.data) {
+				got = append(got, strings.Join(m.labels, ","))
+			}
+			sort.Strings(got)
+			if strings.Join(got, ";") != strings.Join(tt.want, ";") {
+				t.Errorf("numericProbeMetrics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func distributionJob(metrics ...model.TaskMetric) (j model.ScheduleJob) {
+	j = model.ScheduleJob{Name: "job", ScriptFile: "script.yml", Script: &model.Script{}}
+	for i, m := range metrics {
+		j.Script.Tasks = append(j.Script.Tasks, model.NewTask([]string{"a", "b"}[i], "", m, nil))
+	}
+	return
+}
+
+//nolint:funlen
+func Test_distributionTracker(t *testing.T) {
+	j := distributionJob(
+		model.TaskMetric{Type: model.MetricTypeHistogram, Buckets: []float64{100, 1000}, Fields: []string{"timings"}},
+		model.TaskMetric{Type: model.MetricTypeSummary, Quantiles: []float64{0.5}},
+	)
+	s := &Scheduler{logger: model.NewChainLogger(logger, "scheduler")}
+	s.addJob(j)
+	d := s.states["job"].distributions
+	d.add(j, distributionResult(50, 500))
+	d.add(j, distributionResult(500, 700))
+	r := distributionResult(5000, 5000)
+	r.Tasks[0].Status = string(model.EStatusSkipped)
+	d.add(j, r)
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(distributionCollector{d})
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, f := range families {
+		for _, m := range f.Metric {
+			labels := make([]string, 0, len(m.Label))
+			for _, l := range m.Label {
+				if l.GetName() != "job" && l.GetName() != "script" {
+					labels = append(labels, l.GetName()+"="+l.GetValue())
+				}
+			}
+			var value string
+			switch {
+			case m.Histogram != nil:
+				var buckets []string
+				for _, b := range m.Histogram.Bucket {
+					buckets = append(buckets, formatFloat(b.GetUpperBound())+":"+formatFloat(float64(b.GetCumulativeCount())))
+				}
+				value = formatFloat(m.Histogram.GetSampleSum()) + " " + strings.Join(buckets, ",")
+			case m.Summary != nil:
+				value = formatFloat(m.Summary.GetSampleSum()) + " " + formatFloat(float64(m.Summary.GetSampleCount()))
+			default:
+				continue
+			}
+			got[f.GetName()+"{"+strings.Join(labels, ",")+"}"] = value
+		}
+	}
+	want := map[string]string{
+		"boogieman_task_runtime_histogram{task=a}":                                        "550 100:1,1000:2",
+		"boogieman_probe_data_histogram{field=timings,item=https://a/,probe=web,task=a}":  "275 100:1,1000:2",
+		"boogieman_task_runtime_summary{task=b}":                                          "6200 3",
+		"boogieman_probe_data_summary{field=timings,item=https://a/,probe=web,task=b}":    "3100 3",
+		"boogieman_probe_data_summary{field=httpStatus,item=https://a/,probe=web,task=b}": "600 3",
+	}
+	if len(got) != len(want) {
+		t.Errorf("distributions = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%v = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+// distributionCollector collects the metrics of the tracker only
+type distributionCollector struct {
+	*distributionTracker
+}
+
+func (c distributionCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c distributionCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
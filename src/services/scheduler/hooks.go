@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/probefactory"
+	cmdprobe "boogieman/src/probes/cmd"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// environment variables passed to the hook commands, the result json is also passed to the standard input
+const (
+	hookEnvJob     = "BOOGIEMAN_JOB"
+	hookEnvHook    = "BOOGIEMAN_HOOK"
+	hookEnvStatus  = "BOOGIEMAN_STATUS"
+	hookEnvSuccess = "BOOGIEMAN_SUCCESS"
+	hookEnvResult  = "BOOGIEMAN_RESULT"
+)
+
+// startHooks runs the job hooks in background after the result is saved and notified,
+// so a slow hook doesn't delay the next runs of the job, the hooks are stopped after the sum of their timeouts,
+// the hook results are kept in the job state
+func (s *Scheduler) startHooks(logger model.Logger, j model.ScheduleJob, prev, cur model.ScriptResult, st *jobState) {
+	hooks := j.Hooks()
+	if len(hooks) == 0 {
+		return
+	}
+	var timeout time.Duration
+	for _, h := range hooks {
+		timeout += h.RunTimeout()
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		results := s.runHooks(ctx, logger, j, prev, cur)
+		if st != nil {
+			st.setHooks(cur.RunCounter, results)
+		}
+	}()
+}
+
+// runHooks starts the job hooks matching the result one by one,
+// a failure is a failed run after a successful one or the first failed run
+func (s *Scheduler) runHooks(ctx context.Context, logger model.Logger, j model.ScheduleJob, prev, cur model.ScriptResult) (results []model.HookResult) {
+	prevFailed := prev.RunCounter > 0 && !prev.Success
+	hooks := []struct {
+		name string
+		hook *model.JobHook
+		run  bool
+	}{
+		{model.HookOnFailure, j.OnFailure, !cur.Success && !prevFailed},
+		{model.HookOnRecovery, j.OnRecovery, cur.Success && prevFailed},
+		{model.HookOnEveryRun, j.OnEveryRun, true},
+	}
+	for _, h := range hooks {
+		if h.hook == nil || !h.run {
+			continue
+		}
+		if !h.hook.Start(time.Now()) {
+			logger.Printf("%v hook is skipped, it's still running or the cooldown isn't over\n", h.name)
+			continue
+		}
+		r := runHook(ctx, logger, j.Name, h.name, h.hook, cur)
+		h.hook.SetResult(r)
+		if r.Success {
+			logger.Printf("%v hook has been finished, %vms\n", h.name, r.RuntimeMs)
+		} else {
+			logger.Printf("%v hook has failed with exit code %v: %v, %vms\n", h.name, r.ExitCode, r.Error, r.RuntimeMs)
+		}
+		results = append(results, r)
+	}
+	return
+}
+
+// runHook starts the hook command with the cmd probe
+func runHook(ctx context.Context, logger model.Logger, job, name string, hook *model.JobHook, result model.ScriptResult) (r model.HookResult) {
+	// the exit code is -1 if the command isn't started or exited
+	r = model.HookResult{Hook: name, StartedAt: time.Now(), ExitCode: -1}
+	defer func() {
+		r.RuntimeMs = int(time.Since(r.StartedAt).Milliseconds())
+	}()
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	options := model.DefaultProbeOptions
+	options.Timeout = hook.RunTimeout()
+	p, err := probefactory.NewProbe("cmd", options, &cmdprobe.Config{
+		Cmd: hook.Cmd,
+		Env: map[string]string{
+			hookEnvJob:     job,
+			hookEnvHook:    name,
+			hookEnvStatus:  result.Status,
+			hookEnvSuccess: strconv.FormatBool(result.Success),
+			hookEnvResult:  string(resultJSON),
+		},
+		Stdin: string(resultJSON),
+	})
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+
+	ctx = model.ContextWithLogger(ctx, model.NewChainLogger(logger, name))
+	r.Success = p.Start(ctx)
+	p.Finish(ctx)
+	err = p.Error()
+	interrupted := errors.Is(err, cmdprobe.ErrTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+	if data, ok := p.ResultFinished().Data.(cmdprobe.ResultData); ok && !interrupted {
+		r.ExitCode = data.ExitCode
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//nolint:funlen
+func Test_RunHooks(t *testing.T) {
+	dir := t.TempDir()
+	s := &Scheduler{logger: model.NewChainLogger(logger, "scheduler")}
+	ctx := context.Background()
+	// the hook saves its environment and the standard input to the files named by the hook
+	hook := func(cooldown time.Duration) *model.JobHook {
+		return &model.JobHook{
+			Cmd:      `sh -c 'echo "$BOOGIEMAN_JOB $BOOGIEMAN_STATUS $BOOGIEMAN_SUCCESS" > ` + dir + `/$BOOGIEMAN_HOOK.env; cat > ` + dir + `/$BOOGIEMAN_HOOK.json; exit 3'`,
+			Cooldown: cooldown,
+			Timeout:  5000,
+		}
+	}
+	j := model.ScheduleJob{Name: "job", OnFailure: hook(60000), OnRecovery: hook(0), OnEveryRun: hook(0)}
+
+	hooks := func(results []model.HookResult) (names []string) {
+		for _, r := range results {
+			names = append(names, r.Hook)
+		}
+		return
+	}
+	tests := []struct {
+		name string
+		prev model.ScriptResult
+		cur  model.ScriptResult
+		want []string
+	}{
+		{"first failure", model.ScriptResult{}, finishedResult(1, false), []string{model.HookOnFailure, model.HookOnEveryRun}},
+		{"still failed", finishedResult(1, false), finishedResult(2, false), []string{model.HookOnEveryRun}},
+		{"recovery", finishedResult(2, false), finishedResult(3, true), []string{model.HookOnRecovery, model.HookOnEveryRun}},
+		{"still succeeded", finishedResult(3, true), finishedResult(4, true), []string{model.HookOnEveryRun}},
+		{"failure within cooldown", finishedResult(4, true), finishedResult(5, false), []string{model.HookOnEveryRun}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := s.runHooks(ctx, s.logger, j, tt.prev, tt.cur)
+			if got := hooks(results); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("hooks = %v, want %v", got, tt.want)
+			}
+			for _, r := range results {
+				if r.ExitCode != 3 || r.Success || r.Error == "" {
+					t.Errorf("%v: wrong result %+v", r.Hook, r)
+				}
+			}
+		})
+	}
+
+	env, _ := os.ReadFile(filepath.Join(dir, model.HookOnRecovery+".env"))
+	if string(env) != "job finished true\n" {
+		t.Errorf("wrong hook environment %q", env)
+	}
+	var result model.ScriptResult
+	data, _ := os.ReadFile(filepath.Join(dir, model.HookOnRecovery+".json"))
+	if err := json.Unmarshal(data, &result); err != nil || result.RunCounter != 3 {
+		t.Errorf("the result should be passed to the standard input, got %s", data)
+	}
+	if r, runs := j.OnEveryRun.LastResult(); runs != 5 || r.ExitCode != 3 {
+		t.Errorf("the last result should be saved, got %+v, %v runs", r, runs)
+	}
+	if _, runs := j.OnFailure.LastResult(); runs != 1 {
+		t.Errorf("the hook within the cooldown shouldn't be counted, got %v runs", runs)
+	}
+}
+
+func Test_RunHookTimeout(t *testing.T) {
+	s := &Scheduler{logger: model.NewChainLogger(logger, "scheduler")}
+	j := model.ScheduleJob{Name: "job", OnEveryRun: &model.JobHook{Cmd: "sleep 5", Timeout: 100}}
+	results := s.runHooks(context.Background(), s.logger, j, model.ScriptResult{}, finishedResult(1, true))
+	if len(results) != 1 || results[0].Success || results[0].ExitCode != -1 || results[0].RuntimeMs >= 5000 {
+		t.Errorf("the hook should be stopped by timeout, got %+v", results)
+	}
+}
+
+func Test_RunScriptHooksInBackground(t *testing.T) {
+	s := &Scheduler{
+		logger:  model.NewChainLogger(logger, "scheduler"),
+		history: history.NewMemory(history.Retention{}),
+	}
+	j := model.ScheduleJob{
+		Name:       "job",
+		Script:     &model.Script{},
+		OnEveryRun: &model.JobHook{Cmd: "sleep 0.5", Timeout: 5000},
+	}
+	s.addJob(j)
+
+	// the result is saved without waiting for the hook
+	started := time.Now()
+	s.runScript(context.Background(), j)
+	if d := time.Since(started); d >= 500*time.Millisecond {
+		t.Fatalf("the run should not wait for the hook, took %v", d)
+	}
+	if results, _ := s.history.Query(history.Query{Job: "job"}); len(results) != 1 {
+		t.Fatalf("the result should be saved before the hook is finished, got %v", len(results))
+	}
+	// the hook isn't started again while it's running
+	for i := 0; i < 100; i++ {
+		if _, runs := j.OnEveryRun.LastResult(); runs == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.runScript(context.Background(), j)
+	for i := 0; i < 200; i++ {
+		if hr, _ := j.OnEveryRun.LastResult(); hr.RuntimeMs > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, runs := j.OnEveryRun.LastResult(); runs != 1 {
+		t.Fatalf("the running hook shouldn't be started again, got %v runs", runs)
+	}
+	// the hook results are shown with the result of the run they've been started after
+	first, last := finishedResult(1, true), j.Script.ResultFinished()
+	for i := 0; i < 100 && len(first.Hooks) == 0; i++ {
+		time.Sleep(time.Millisecond)
+		s.getJobState("job").apply(&first)
+	}
+	s.getJobState("job").apply(&last)
+	if len(first.Hooks) != 1 || !first.Hooks[0].Success {
+		t.Fatalf("the hook results should be kept, got %+v", first.Hooks)
+	}
+	if len(last.Hooks) != 0 {
+		t.Fatalf("the hooks of the first run shouldn't be applied to the second one, got %+v", last.Hooks)
+	}
+}
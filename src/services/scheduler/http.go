@@ -2,10 +2,17 @@ package scheduler
 
 import (
 	"boogieman/src/model"
+	"boogieman/src/services/history"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
+const defaultHistoryLimit = 100
+
 func (s *Scheduler) httpJob(req *http.Request) (code int, jsonData []byte) {
 	code = http.StatusOK
 	jobName := req.URL.Query().Get("name")
@@ -19,7 +26,11 @@ func (s *Scheduler) httpJob(req *http.Request) (code int, jsonData []byte) {
 		code = http.StatusNotFound
 		return
 	}
-	jsonData, err = json.Marshal(j.Script.ResultFinished())
+	r := j.Script.ResultFinished()
+	if st := s.getJobState(jobName); st != nil {
+		st.apply(&r)
+	}
+	jsonData, err = json.Marshal(r)
 	if err != nil {
 		code = http.StatusInternalServerError
 		s.logger.Printf("httpJob: can't create json response: %v\n", err)
@@ -31,10 +42,15 @@ func (s *Scheduler) httpJobs(*http.Request) (code int, jsonData []byte) {
 	code = http.StatusOK
 
 	jobs := make([]model.ScheduleJob, 0)
+	s.Lock()
+	defer s.Unlock()
 	for _, j := range s.jobs {
 		if j.CronJob != nil {
 			j.NextStartAt = j.CronJob.NextRun()
 		}
+		if st := s.states[j.Name]; st != nil {
+			j.StateTimes = st.times.scriptTimes()
+		}
 		jobs = append(jobs, j)
 	}
 	jsonData, err := json.Marshal(jobs)
@@ -44,3 +60,53 @@ func (s *Scheduler) httpJobs(*http.Request) (code int, jsonData []byte) {
 	}
 	return
 }
+
+// httpJobHistory returns the job results, from and to are RFC3339 times or unix timestamps,
+// the last 100 results are returned by default
+func (s *Scheduler) httpJobHistory(req *http.Request) (code int, jsonData []byte) {
+	code = http.StatusOK
+	params := req.URL.Query()
+	q := history.Query{Job: params.Get("name"), Limit: defaultHistoryLimit}
+	if q.Job == "" {
+		return httpError(http.StatusBadRequest, errors.New("name should be defined"))
+	}
+	var err error
+	if q.From, err = parseTime(params.Get("from")); err != nil {
+		return httpError(http.StatusBadRequest, fmt.Errorf("wrong from: %w", err))
+	}
+	if q.To, err = parseTime(params.Get("to")); err != nil {
+		return httpError(http.StatusBadRequest, fmt.Errorf("wrong to: %w", err))
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			return httpError(http.StatusBadRequest, fmt.Errorf("wrong limit %v", limit))
+		}
+	}
+
+	h := s.getHistory()
+	if h == nil {
+		return httpError(http.StatusNotFound, errors.New("history is disabled"))
+	}
+	results, err := h.Query(q)
+	if err != nil {
+		s.logger.Printf("httpJobHistory: %v\n", err)
+		return httpError(http.StatusInternalServerError, err)
+	}
+	jsonData, err = json.Marshal(results)
+	if err != nil {
+		code = http.StatusInternalServerError
+		s.logger.Printf("httpJobHistory: can't create json response: %v\n", err)
+	}
+	return
+}
+
+// parseTime parses RFC3339 time or unix timestamp in seconds, empty string is zero time
+func parseTime(s string) (t time.Time, err error) {
+	if s == "" {
+		return
+	}
+	if sec, e := strconv.ParseInt(s, 10, 64); e == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
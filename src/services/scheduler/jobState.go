@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"sync"
+	"time"
+)
+
+// jobState is computed from the job results between runs, it's reset when the job is changed
+type jobState struct {
+	stability     *stabilityTracker
+	availability  *availabilityTracker
+	distributions *distributionTracker
+	times         *timesTracker
+	hooksLock     sync.Mutex
+	hooksRun      uint               // the run counter of the result the hooks have been started after
+	hooks         []model.HookResult // the results of the hooks started after the run
+}
+
+// newJobState creates the state of the job, the availability and the times are restored from the history
+func (s *Scheduler) newJobState(j model.ScheduleJob) *jobState {
+	s.Lock()
+	windows, h := s.availabilityWindows, s.history
+	s.Unlock()
+	st := &jobState{
+		stability:     newStabilityTracker(j.Stability),
+		availability:  newAvailabilityTracker(windows),
+		distributions: newDistributionTracker(),
+		times:         newTimesTracker(),
+	}
+	// the results of the replaced job are also counted
+	if err := st.availability.restore(h, j.Name, time.Now()); err != nil {
+		s.logger.Printf("[%v] can't restore the availability from the history: %v\n", j.Name, err)
+	}
+	if err := st.times.restore(h, j.Name); err != nil {
+		s.logger.Printf("[%v] can't restore the run times from the history: %v\n", j.Name, err)
+	}
+	return st
+}
+
+// add counts the finished run and sets the computed fields of the result
+func (st *jobState) add(j model.ScheduleJob, r *model.ScriptResult) {
+	st.stability.add(r)
+	st.availability.add(*r)
+	st.distributions.add(j, *r)
+	st.times.add(r)
+}
+
+// apply sets the current computed fields of the result and the results of its finished hooks
+func (st *jobState) apply(r *model.ScriptResult) {
+	st.stability.apply(r)
+	st.times.apply(r)
+	st.hooksLock.Lock()
+	if st.hooksRun == r.RunCounter {
+		r.Hooks = st.hooks
+	}
+	st.hooksLock.Unlock()
+}
+
+// setHooks keeps the results of the hooks started after the run
+func (st *jobState) setHooks(run uint, hooks []model.HookResult) {
+	st.hooksLock.Lock()
+	defer st.hooksLock.Unlock()
+	st.hooksRun, st.hooks = run, hooks
+}
+
+func (s *Scheduler) getJobState(job string) *jobState {
+	s.Lock()
+	defer s.Unlock()
+	return s.states[job]
+}
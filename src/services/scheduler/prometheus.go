@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -13,20 +14,48 @@ import (
 var (
 	LabelsScriptGeneral        = []string{"job", "script"}
 	LabelsTaskGeneral          = []string{"job", "script", "task"}
+	LabelsTaskStatus           = []string{"job", "script", "task", "status"}
+	LabelsTaskWindow           = []string{"job", "script", "task", "window"}
+	LabelsTaskReason           = []string{"job", "script", "task", "reason"}
 	LabelsProbeDataGeneral     = []string{"job", "script", "task", "probe"}
 	LabelsProbeDateItemGeneral = []string{"job", "script", "task", "probe", "item"}
+	LabelsHookGeneral          = []string{"job", "script", "hook"}
 )
 
 const (
-	probeDataHelpDescr = "probe execution data result"
-	pNameData          = "boogieman_probe_data"
-	pNameDataItem      = "boogieman_probe_data_item"
-	pNameScriptResult  = "boogieman_script_result"
-	pNameTaskResult    = "boogieman_task_result"
-	pNameTaskRuntime   = "boogieman_task_runtime"
-	pNameTaskRuns      = "boogieman_task_runs"
+	probeDataHelpDescr        = "probe execution data result"
+	pNameData                 = "boogieman_probe_data"
+	pNameDataItem             = "boogieman_probe_data_item"
+	pNameScriptResult         = "boogieman_script_result"
+	pNameScriptTimeout        = "boogieman_script_timeouts_total"
+	pNameTaskResult           = "boogieman_task_result"
+	pNameTaskRuntime          = "boogieman_task_runtime"
+	pNameTaskRuns             = "boogieman_task_runs"
+	pNameTaskStatus           = "boogieman_task_status"
+	pNameTaskAttempts         = "boogieman_task_attempts"
+	pNameTaskFailure          = "boogieman_task_failure"
+	pNameTaskStable           = "boogieman_task_stable_result"
+	pNameTaskFlapping         = "boogieman_task_flapping"
+	pNameTaskAvailability     = "boogieman_task_availability_ratio"
+	pNameTaskErrorBudget      = "boogieman_task_error_budget_remaining"
+	pNameScriptLastRun        = "boogieman_script_last_run_timestamp_seconds"
+	pNameScriptLastSuccess    = "boogieman_script_last_success_timestamp_seconds"
+	pNameScriptLastChange     = "boogieman_script_last_change_timestamp_seconds"
+	pNameTaskLastRun          = "boogieman_task_last_run_timestamp_seconds"
+	pNameTaskLastSuccess      = "boogieman_task_last_success_timestamp_seconds"
+	pNameTaskLastChange       = "boogieman_task_last_change_timestamp_seconds"
+	pNameTaskRuntimeHistogram = "boogieman_task_runtime_histogram"
+	pNameTaskRuntimeSummary   = "boogieman_task_runtime_summary"
+	pNameDataHistogram        = "boogieman_probe_data_histogram"
+	pNameDataSummary          = "boogieman_probe_data_summary"
+	pNameHookExitCode         = "boogieman_job_hook_exit_code"
+	pNameHookRuntime          = "boogieman_job_hook_runtime"
+	pNameHookRuns             = "boogieman_job_hook_runs"
 )
 
+// taskStatuses are the statuses a finished task can have, exported as the task status state set
+var taskStatuses = []model.EStatus{model.EStatusFinished, model.EStatusTimeout, model.EStatusSkipped}
+
 // probe metrics struct
 type metricData struct {
 	valueType   prometheus.ValueType
@@ -46,6 +75,9 @@ var metricBaseDescriptors = map[string]metricDescriptorInfo{
 	pNameScriptResult: {
 		pNameScriptResult, "script execution result", LabelsScriptGeneral,
 	},
+	pNameScriptTimeout: {
+		pNameScriptTimeout, "number of script runs interrupted by timeout", LabelsScriptGeneral,
+	},
 	pNameTaskResult: {
 		pNameTaskResult, "task execution result", LabelsTaskGeneral,
 	},
@@ -55,6 +87,54 @@ var metricBaseDescriptors = map[string]metricDescriptorInfo{
 	pNameTaskRuns: {
 		pNameTaskRuns, "task run counter", LabelsTaskGeneral,
 	},
+	pNameTaskStatus: {
+		pNameTaskStatus, "task status of the last run, 1 for the current status", LabelsTaskStatus,
+	},
+	pNameTaskAttempts: {
+		pNameTaskAttempts, "number of probe attempts in the last task run", LabelsTaskGeneral,
+	},
+	pNameTaskFailure: {
+		pNameTaskFailure, "number of probe targets failed by the reason in the last task run, sent for failed tasks only", LabelsTaskReason,
+	},
+	pNameTaskStable: {
+		pNameTaskStable, "stable task result, changed after failThreshold failures or recoverThreshold successes in a row", LabelsTaskGeneral,
+	},
+	pNameTaskFlapping: {
+		pNameTaskFlapping, "1 if the task result is changed flapThreshold times within the latest results", LabelsTaskGeneral,
+	},
+	pNameTaskAvailability: {
+		pNameTaskAvailability, "share of successful task runs within the window", LabelsTaskWindow,
+	},
+	pNameTaskErrorBudget: {
+		pNameTaskErrorBudget, "share of the task error budget left within the longest window, negative if the slo is violated", LabelsTaskGeneral,
+	},
+	pNameScriptLastRun: {
+		pNameScriptLastRun, "start time of the last script run", LabelsScriptGeneral,
+	},
+	pNameScriptLastSuccess: {
+		pNameScriptLastSuccess, "start time of the last successful script run", LabelsScriptGeneral,
+	},
+	pNameScriptLastChange: {
+		pNameScriptLastChange, "start time of the last script run with the result changed", LabelsScriptGeneral,
+	},
+	pNameTaskLastRun: {
+		pNameTaskLastRun, "start time of the last task run", LabelsTaskGeneral,
+	},
+	pNameTaskLastSuccess: {
+		pNameTaskLastSuccess, "start time of the last successful task run", LabelsTaskGeneral,
+	},
+	pNameTaskLastChange: {
+		pNameTaskLastChange, "start time of the last task run with the result changed", LabelsTaskGeneral,
+	},
+	pNameHookExitCode: {
+		pNameHookExitCode, "exit code of the last job hook command, -1 if it isn't exited", LabelsHookGeneral,
+	},
+	pNameHookRuntime: {
+		pNameHookRuntime, "runtime of the last job hook command", LabelsHookGeneral,
+	},
+	pNameHookRuns: {
+		pNameHookRuns, "job hook command run counter", LabelsHookGeneral,
+	},
 	pNameData: {
 		pNameData, probeDataHelpDescr, LabelsProbeDataGeneral,
 	},
@@ -78,20 +158,47 @@ func (s *Scheduler) Describe(chan<- *prometheus.Desc) {
 func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
 	s.Lock()
 	defer s.Unlock()
+	now := time.Now()
 	for _, j := range s.jobs {
 		scriptResult := j.Script.ResultFinished()
+		st := s.states[j.Name]
+		if st == nil {
+			st = &jobState{}
+		}
+		st.distributions.collect(ch)
+		s.sendTimesMetrics(ch, [3]string{pNameScriptLastRun, pNameScriptLastSuccess, pNameScriptLastChange},
+			st.times.scriptTimes(), []string{j.Name, j.ScriptFile}, nil)
 		s.sendMetric(ch, []string{pNameScriptResult},
 			metricData{prometheus.GaugeValue, gbValue(scriptResult.Success), []string{j.Name, j.ScriptFile}, nil, nil},
 		)
+		s.sendMetric(ch, []string{pNameScriptTimeout},
+			metricData{prometheus.CounterValue, float64(scriptResult.Timeouts), []string{j.Name, j.ScriptFile}, nil, nil},
+		)
+		for name, hook := range j.Hooks() {
+			r, runs := hook.LastResult()
+			if runs == 0 {
+				continue
+			}
+			hookLabelValues := []string{j.Name, j.ScriptFile, name}
+			s.sendMetric(ch, []string{pNameHookExitCode},
+				metricData{prometheus.GaugeValue, float64(r.ExitCode), hookLabelValues, nil, nil})
+			s.sendMetric(ch, []string{pNameHookRuntime},
+				metricData{prometheus.GaugeValue, float64(r.RuntimeMs), hookLabelValues, nil, nil})
+			s.sendMetric(ch, []string{pNameHookRuns},
+				metricData{prometheus.CounterValue, float64(runs), hookLabelValues, nil, nil})
+		}
 		for _, t := range scriptResult.Tasks {
 			// task general metrics
 			taskMetricLabelValues := []string{j.Name, j.ScriptFile, t.Name}
 
 			// check if there are additional metric labels for this task
-			var taskMetric model.TaskMetric
+			var (
+				taskMetric model.TaskMetric
+				slo        float64
+			)
 			for _, task := range j.Script.Tasks {
 				if task.Name == t.Name {
-					taskMetric = task.Metric
+					taskMetric, slo = task.Metric, task.SLO
 					break
 				}
 			}
@@ -104,6 +211,46 @@ func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
 			s.sendMetric(
 				ch, []string{pNameTaskRuns},
 				metricData{prometheus.CounterValue, float64(t.RunCounter), taskMetricLabelValues, nil, taskMetric.Labels.Data()})
+			s.sendMetric(
+				ch, []string{pNameTaskAttempts},
+				metricData{prometheus.GaugeValue, float64(len(t.Attempts)), taskMetricLabelValues, nil, taskMetric.Labels.Data()})
+			if state, ok := st.stability.state(t.Name); ok {
+				s.sendMetric(
+					ch, []string{pNameTaskStable},
+					metricData{prometheus.GaugeValue, gbValue(state.StableSuccess), taskMetricLabelValues, nil, taskMetric.Labels.Data()})
+				s.sendMetric(
+					ch, []string{pNameTaskFlapping},
+					metricData{prometheus.GaugeValue, gbValue(state.Flapping), taskMetricLabelValues, nil, taskMetric.Labels.Data()})
+			}
+			s.sendTimesMetrics(ch, [3]string{pNameTaskLastRun, pNameTaskLastSuccess, pNameTaskLastChange},
+				st.times.taskTimes(t.Name), taskMetricLabelValues, taskMetric.Labels.Data())
+			availability := st.availability.availability(t.Name, now)
+			for _, a := range availability {
+				s.sendMetric(
+					ch, []string{pNameTaskAvailability},
+					metricData{prometheus.GaugeValue, a.ratio, addToArray(taskMetricLabelValues, a.window.Name), nil, taskMetric.Labels.Data()})
+			}
+			if remaining, ok := errorBudgetRemaining(availability, slo); ok {
+				s.sendMetric(
+					ch, []string{pNameTaskErrorBudget},
+					metricData{prometheus.GaugeValue, remaining, taskMetricLabelValues, nil, taskMetric.Labels.Data()})
+			}
+			for _, status := range taskStatuses {
+				s.sendMetric(
+					ch, []string{pNameTaskStatus},
+					metricData{
+						prometheus.GaugeValue, gbValue(t.Status == string(status)),
+						addToArray(taskMetricLabelValues, string(status)), nil, taskMetric.Labels.Data(),
+					})
+			}
+			for reason, count := range taskFailureReasons(t) {
+				s.sendMetric(
+					ch, []string{pNameTaskFailure},
+					metricData{
+						prometheus.GaugeValue, float64(count),
+						addToArray(taskMetricLabelValues, string(reason)), nil, taskMetric.Labels.Data(),
+					})
+			}
 
 			// task data metrics
 			if t.Probe.Data != nil {
@@ -121,18 +268,7 @@ func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
 						pDescriptorKey = []string{pNameData}
 					} else {
 						labelValues = dataMetricLabelValues[:]
-						if len(taskMetric.ValueMap) > 0 {
-							for i, labelName := range m.labelNames {
-								if labelName != "item" {
-									continue
-								}
-								val, ok := taskMetric.ValueMap[m.labels[i]]
-								if ok {
-									m.labels[i] = val
-								}
-								break
-							}
-						}
+						mapItemLabel(&m, taskMetric.ValueMap)
 						labelValues = append(labelValues, m.labels...)
 						pDescriptorKey = []string{pNameDataItem, strings.Join(m.labelNames, ",")}
 					}
@@ -152,6 +288,38 @@ func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// sendTimesMetrics sends the last run, success and change times as unix timestamps, unknown times aren't sent
+func (s *Scheduler) sendTimesMetrics(ch chan<- prometheus.Metric, names [3]string, times *model.StateTimes, labels []string, constLabels prometheus.Labels) {
+	if times == nil {
+		return
+	}
+	for i, t := range []*time.Time{times.LastRunAt, times.LastSuccessAt, times.LastChangeAt} {
+		if t != nil {
+			s.sendMetric(ch, []string{names[i]},
+				metricData{prometheus.GaugeValue, float64(t.UnixNano()) / 1e9, labels, nil, constLabels})
+		}
+	}
+}
+
+// taskFailureReasons returns the number of failed probe targets by the failure reasons,
+// nil if the task hasn't failed
+func taskFailureReasons(t model.TaskResult) map[model.ErrorReason]int {
+	if t.Success || t.Status == string(model.EStatusSkipped) || t.Status == string(model.EStatusNew) {
+		return nil
+	}
+	if t.Status == string(model.EStatusTimeout) {
+		return map[model.ErrorReason]int{model.ReasonTimeout: 1}
+	}
+	reasons := make(map[model.ErrorReason]int)
+	for _, e := range t.Probe.Errors {
+		reasons[e.Reason]++
+	}
+	if len(reasons) == 0 {
+		reasons[model.ReasonUnknown] = 1
+	}
+	return reasons
+}
+
 // gbValue returns a gauge value for boolean data (1 for true, 0 - false)
 func gbValue(v bool) float64 {
 	if v {
@@ -350,7 +518,7 @@ func probeStructMetrics(v reflect.Value) (metrics []metricData) {
 		}
 
 		if fv.Kind() == reflect.Map {
-			metrics = append(metrics, probeStructMapMetrics(fieldName, fv)...)
+			metrics = append(metrics, probeStructMapMetrics(fieldName, nestedKeyLabel(field), fv)...)
 			continue
 		}
 
@@ -363,8 +531,14 @@ func probeStructMetrics(v reflect.Value) (metrics []metricData) {
 	return metrics
 }
 
-func probeStructMapMetrics(fieldName string, v reflect.Value) (metrics []metricData) {
-	if v.Type().Key().Kind() != reflect.String {
+// probeStructMapMetrics returns the metrics of the map items with the field and item labels,
+// the items of nested maps also get the keyLabel label
+func probeStructMapMetrics(fieldName string, keyLabel string, v reflect.Value) (metrics []metricData) {
+	return probeMapMetrics(v, []string{fieldName}, []string{"field", "item", keyLabel})
+}
+
+func probeMapMetrics(v reflect.Value, labels []string, labelNames []string) (metrics []metricData) {
+	if v.Type().Key().Kind() != reflect.String || len(labels) >= len(labelNames) {
 		return nil
 	}
 
@@ -374,17 +548,18 @@ func probeStructMapMetrics(fieldName string, v reflect.Value) (metrics []metricD
 	})
 	for _, k := range keys {
 		value := v.MapIndex(k)
-		if value.Kind() == reflect.Ptr {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
 			if value.IsNil() {
-				continue
+				break
 			}
 			value = value.Elem()
 		}
+		itemLabels := addToArray(labels, k.String())
 
 		m := metricData{
 			valueType:  prometheus.GaugeValue,
-			labels:     []string{fieldName, k.String()},
-			labelNames: []string{"field", "item"},
+			labels:     itemLabels,
+			labelNames: labelNames[:len(itemLabels)],
 		}
 		switch {
 		case reflectIsInt(value.Kind()):
@@ -396,7 +571,10 @@ func probeStructMapMetrics(fieldName string, v reflect.Value) (metrics []metricD
 		case value.Kind() == reflect.String:
 			m.value = stringMetricValue(value.String())
 			m.labels = append(m.labels, value.String())
-			m.labelNames = append(m.labelNames, "value")
+			m.labelNames = addToArray(m.labelNames, "value")
+		case value.Kind() == reflect.Map:
+			metrics = append(metrics, probeMapMetrics(value, itemLabels, labelNames)...)
+			continue
 		default:
 			continue
 		}
@@ -446,6 +624,15 @@ func mapKeyString(v reflect.Value) string {
 	}
 }
 
+// nestedKeyLabel returns the label name of the nested map keys of the probe data field set by the metricKey tag,
+// key by default
+func nestedKeyLabel(field reflect.StructField) string {
+	if label := field.Tag.Get("metricKey"); label != "" {
+		return label
+	}
+	return "key"
+}
+
 func exportedFieldName(field reflect.StructField) string {
 	jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
 	switch jsonName {
@@ -1,7 +1,9 @@
 package scheduler
 
 import (
+	"boogieman/src/model"
 	"github.com/prometheus/client_golang/prometheus"
+	"reflect"
 	"testing"
 )
 
@@ -203,6 +205,50 @@ func Test_probeDataMetric(t *testing.T) {
 				},
 			},
 		},
+		{
+			data: struct {
+				JSON map[string]map[string]any `json:"json"`
+			}{
+				JSON: map[string]map[string]any{
+					"https://example.com/": {"depth": float64(12), "items": []any{1}, "status": "ok", "up": true},
+				},
+			},
+			expectedResult: []expectedResult{
+				{
+					prometheus.GaugeValue,
+					float64(12),
+					[]string{"json", "https://example.com/", "depth"},
+					[]string{"field", "item", "key"},
+				},
+				{
+					prometheus.GaugeValue,
+					float64(1),
+					[]string{"json", "https://example.com/", "status", "ok"},
+					[]string{"field", "item", "key", "value"},
+				},
+				{
+					prometheus.GaugeValue,
+					float64(1),
+					[]string{"json", "https://example.com/", "up"},
+					[]string{"field", "item", "key"},
+				},
+			},
+		},
+		{
+			data: struct {
+				Phases map[string]map[string]float64 `json:"phases" metricKey:"phase"`
+			}{
+				Phases: map[string]map[string]float64{"https://example.com/": {"dns": 1.5}},
+			},
+			expectedResult: []expectedResult{
+				{
+					prometheus.GaugeValue,
+					1.5,
+					[]string{"phases", "https://example.com/", "dns"},
+					[]string{"field", "item", "phase"},
+				},
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -267,3 +313,33 @@ func Test_sendMetricProbeDataItemWithDynamicLabels(t *testing.T) {
 		},
 	)
 }
+
+func Test_taskFailureReasons(t *testing.T) {
+	failed := func(status model.EStatus, errs map[string]model.ProbeError) (r model.TaskResult) {
+		r.Status = string(status)
+		r.Probe.Errors = errs
+		return
+	}
+	tests := []struct {
+		name string
+		task model.TaskResult
+		want map[model.ErrorReason]int
+	}{
+		{"success", model.TaskResult{Status: string(model.EStatusFinished), Result: model.Result{Success: true}}, nil},
+		{"skipped", failed(model.EStatusSkipped, nil), nil},
+		{"timeout", failed(model.EStatusTimeout, nil), map[model.ErrorReason]int{model.ReasonTimeout: 1}},
+		{"no target errors", failed(model.EStatusFinished, nil), map[model.ErrorReason]int{model.ReasonUnknown: 1}},
+		{"target errors", failed(model.EStatusFinished, map[string]model.ProbeError{
+			"https://a/": {Reason: model.ReasonDNS},
+			"https://b/": {Reason: model.ReasonDNS},
+			"https://c/": {Reason: model.ReasonWrongStatus},
+		}), map[model.ErrorReason]int{model.ReasonDNS: 2, model.ReasonWrongStatus: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taskFailureReasons(tt.task); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("taskFailureReasons() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"time"
+)
+
+// finishedResult returns the finished result of the run with the tasks
+func finishedResult(counter uint, success bool, tasks ...model.TaskResult) (r model.ScriptResult) {
+	r.RunCounter = counter
+	r.Success = success
+	r.Status = string(model.EStatusFinished)
+	r.Tasks = tasks
+	return
+}
+
+// finishedTask returns the finished result of the task
+func finishedTask(name string, success bool) (t model.TaskResult) {
+	t.Name = name
+	t.Status = string(model.EStatusFinished)
+	t.Success = success
+	return
+}
+
+// taskResults returns the result of the run with a task named "task" per the task result
+func taskResults(results ...bool) model.ScriptResult {
+	tasks := make([]model.TaskResult, 0, len(results))
+	for _, success := range results {
+		tasks = append(tasks, finishedTask("task", success))
+	}
+	return finishedResult(0, false, tasks...)
+}
+
+// runResult returns the result of the run with the task started at the time
+func runResult(at time.Time, success bool) (r model.ScriptResult) {
+	r = taskResults(success)
+	r.StartedAt = at
+	r.Tasks[0].StartedAt = at
+	return
+}
+
+// distributionResult returns the result of the run with the web tasks "a" and "b" of the runtimes
+func distributionResult(runtimes ...int) model.ScriptResult {
+	tasks := make([]model.TaskResult, 0, len(runtimes))
+	for i, runtime := range runtimes {
+		t := finishedTask([]string{"a", "b"}[i], false)
+		t.RuntimeMs = runtime
+		t.Probe.Name = "web"
+		t.Probe.Data = struct {
+			Timings map[string]int  `json:"timings"`
+			Status  map[string]int  `json:"httpStatus"`
+			Regex   map[string]bool `json:"regex"`
+		}{map[string]int{"https://a/": runtime / 2}, map[string]int{"https://a/": 200}, map[string]bool{"https://a/": true}}
+		tasks = append(tasks, t)
+	}
+	return finishedResult(0, false, tasks...)
+}
@@ -2,11 +2,13 @@ package scheduler
 
 import (
 	"boogieman/src/model"
+	"boogieman/src/services/history"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/go-co-op/gocron"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,11 +17,13 @@ var logger = model.DefaultLogger
 var defScheduler = Scheduler{
 	jobs:        make([]model.ScheduleJob, 0),
 	urlPatterns: make(map[string]httpHandler),
+	states:      make(map[string]*jobState),
 }
 
 const (
-	httpPathPrefixJob  = "/job"
-	httpPathPrefixJobs = "/jobs"
+	httpPathPrefixJob        = "/job"
+	httpPathPrefixJobs       = "/jobs"
+	httpPathPrefixJobHistory = "/job/history"
 )
 
 type Scheduler struct {
@@ -27,7 +31,22 @@ type Scheduler struct {
 	jobs        []model.ScheduleJob
 	urlPatterns map[string]httpHandler
 	sync.Mutex
-	logger model.Logger
+	logger              model.Logger
+	configurator        Configurator
+	apiToken            string     // bearer token of the jobs API
+	apiLock             sync.Mutex // serializes saving the jobs changed with the API
+	applyLock           sync.Mutex // serializes the changes of the job list
+	history             history.Store
+	notifier            Notifier
+	states              map[string]*jobState // by job name
+	availabilityWindows []AvailabilityWindow
+}
+
+// Notifier is notified about every finished job run
+type Notifier interface {
+	Notify(job string, prev, cur model.ScriptResult)
+	// Forget drops the state of the removed or replaced job
+	Forget(job string)
 }
 
 type httpHandler func(req *http.Request) (code int, jsonData []byte)
@@ -45,6 +64,9 @@ func Run() (s *Scheduler) {
 
 	s.urlPatterns[httpPathPrefixJob] = s.httpJob
 	s.urlPatterns[httpPathPrefixJobs] = s.httpJobs
+	s.urlPatterns[httpPathPrefixJobHistory] = s.httpJobHistory
+	s.history = history.NewMemory(history.Retention{MaxRecords: history.DefaultMaxRecords})
+	s.availabilityWindows, _ = ParseAvailabilityWindows(DefaultAvailabilityWindows)
 
 	s.logger.Println("started")
 	return
@@ -61,38 +83,155 @@ func (s *Scheduler) Shutdown(ctx context.Context) error {
 	case <-ctx.Done():
 		s.logger.Println("Scheduler finishing timeout")
 	}
+	if h := s.getHistory(); h != nil {
+		return h.Close()
+	}
 	return nil
 }
 
+// SetHistory replaces the store of the job results
+func (s *Scheduler) SetHistory(h history.Store) {
+	s.Lock()
+	defer s.Unlock()
+	s.history = h
+}
+
+// SetNotifier sets the notifier about finished job runs
+func (s *Scheduler) SetNotifier(n Notifier) {
+	s.Lock()
+	defer s.Unlock()
+	s.notifier = n
+}
+
+func (s *Scheduler) getHistory() history.Store {
+	s.Lock()
+	defer s.Unlock()
+	return s.history
+}
+
 func (s *Scheduler) AddJob(j model.ScheduleJob) (err error) {
+	s.applyLock.Lock()
+	defer s.applyLock.Unlock()
 	if j.CronJob != nil {
 		return errors.New("already added")
 	}
-	job, err := s.addCronJob(j.Name, j.Script, j.Schedule, j.Once)
-	if err != nil {
-		return
+	if !j.Paused {
+		j.CronJob, err = s.addCronJob(j)
+		if err != nil {
+			return
+		}
 	}
-	j.CronJob = job
 	s.addJob(j)
 	s.logger.Println("add job ", j.Name, " with scenario from ", j.ScriptFile, " at a schedule ", j.Schedule)
 	return
 }
 
+// ApplyJobs replaces the scheduled jobs with the jobs list, jobs are matched by name,
+// unchanged jobs are kept with their results, nothing is changed if any of the new jobs can't be scheduled
+func (s *Scheduler) ApplyJobs(jobs []model.ScheduleJob) (err error) {
+	s.applyLock.Lock()
+	defer s.applyLock.Unlock()
+	return s.applyJobsLocked(jobs)
+}
+
+// updateJobs applies the jobs list changed by the update function, the list isn't changed by others meanwhile
+func (s *Scheduler) updateJobs(update func(jobs []model.ScheduleJob) ([]model.ScheduleJob, error)) error {
+	s.applyLock.Lock()
+	defer s.applyLock.Unlock()
+	jobs, err := update(s.jobsList())
+	if err != nil {
+		return err
+	}
+	return s.applyJobsLocked(jobs)
+}
+
+// applyJobsLocked is ApplyJobs, the caller should hold applyLock
+func (s *Scheduler) applyJobsLocked(jobs []model.ScheduleJob) (err error) {
+	s.Lock()
+	current := make(map[string]model.ScheduleJob, len(s.jobs))
+	for _, j := range s.jobs {
+		current[j.Name] = j
+	}
+	s.Unlock()
+
+	var (
+		changed []model.ScheduleJob
+		names   = make(map[string]bool, len(jobs))
+	)
+	for _, j := range jobs {
+		names[j.Name] = true
+		if c, ok := current[j.Name]; ok && c.SameAs(j) {
+			continue
+		}
+		changed = append(changed, j)
+	}
+
+	// schedules are checked before any change, so an invalid job doesn't break the running configuration
+	check := gocron.NewScheduler(time.Local)
+	for _, j := range changed {
+		if j.Paused {
+			continue
+		}
+		if _, err = newCronJob(check, j, func(model.ScheduleJob, gocron.Job) {}); err != nil {
+			return fmt.Errorf("[%v] %w", j.Name, err)
+		}
+	}
+
+	// removals can't fail, so they're done after all the jobs are swapped,
+	// the swapped jobs are restored if any of them can't be scheduled
+	var swapped []replacedJob
+	for _, j := range changed {
+		if _, ok := current[j.Name]; ok {
+			s.logger.Println("replace job ", j.Name, " with scenario from ", j.ScriptFile, " at a schedule ", j.Schedule)
+		} else {
+			s.logger.Println("add job ", j.Name, " with scenario from ", j.ScriptFile, " at a schedule ", j.Schedule)
+		}
+		prev, e := s.swapJob(j)
+		if e != nil {
+			for i := len(swapped) - 1; i >= 0; i-- {
+				s.restoreJob(swapped[i])
+			}
+			s.logger.Println("jobs are restored")
+			return fmt.Errorf("[%v] %w", j.Name, e)
+		}
+		swapped = append(swapped, prev)
+	}
+	for _, prev := range swapped {
+		if prev.found {
+			s.forgetJob(prev.name)
+		}
+	}
+	for name := range current {
+		if !names[name] {
+			s.delJob(name)
+			s.logger.Println("remove job ", name)
+		}
+	}
+	return
+}
+
 func (s *Scheduler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	resCode := http.StatusNotFound
 	var jsonData []byte
 
-	for k, v := range s.urlPatterns {
-		if req.URL.Path == k {
-			resCode, jsonData = v(req)
-			break
+	handler, ok := s.urlPatterns[req.URL.Path]
+	if !ok {
+		// patterns ending with a slash match the whole subtree like in http.ServeMux
+		for k, v := range s.urlPatterns {
+			if strings.HasSuffix(k, "/") && strings.HasPrefix(req.URL.Path, k) {
+				handler, ok = v, true
+				break
+			}
 		}
 	}
-	if resCode == http.StatusOK {
+	if ok {
+		resCode, jsonData = handler(req)
+	}
+	if jsonData != nil {
 		res.Header().Set("Content-Type", "application/json")
 	}
 	res.WriteHeader(resCode)
-	if resCode == http.StatusOK {
+	if jsonData != nil {
 		_, _ = res.Write(jsonData)
 	} else {
 		_, _ = fmt.Fprint(res, http.StatusText(resCode))
@@ -106,35 +245,145 @@ func (s *Scheduler) URLPatters() (p []string) {
 	return
 }
 
-func (s *Scheduler) addCronJob(name string, script *model.Script, schedule string, once bool) (cronJob *gocron.Job, err error) {
+func (s *Scheduler) addCronJob(j model.ScheduleJob) (cronJob *gocron.Job, err error) {
+	return newCronJob(s.Scheduler, j, func(j model.ScheduleJob, job gocron.Job) {
+		s.runScript(job.Context(), j)
+	})
+}
+
+// newCronJob schedules the job run in the gocron scheduler
+func newCronJob(scheduler *gocron.Scheduler, j model.ScheduleJob, run func(model.ScheduleJob, gocron.Job)) (*gocron.Job, error) {
 	var sj *gocron.Scheduler
-	if _, e := time.ParseDuration(schedule); e == nil {
-		sj = s.Every(schedule)
+	if _, e := time.ParseDuration(j.Schedule); e == nil {
+		sj = scheduler.Every(j.Schedule)
 	} else {
-		sj = s.CronWithSeconds(schedule).WaitForSchedule()
+		sj = scheduler.CronWithSeconds(j.Schedule).WaitForSchedule()
 	}
-	if once {
+	if j.Once {
 		sj = sj.LimitRunsTo(1)
 	}
+	return sj.Name(j.Name).DoWithJobDetails(run, j)
+}
 
-	l := s.logger
-
-	cronJob, err = sj.Name(name).DoWithJobDetails(func(s *model.Script, job gocron.Job) {
-		logger := model.NewChainLogger(l, job.GetName())
-		logger.Println("starting the job")
-		s.Run(model.ContextWithLogger(job.Context(), logger))
-		logger.Println("job has been finished")
-	}, script)
+func (s *Scheduler) runScript(ctx context.Context, j model.ScheduleJob) {
+	name, script := j.Name, j.Script
+	logger := model.NewChainLogger(s.logger, name)
+	logger.Println("starting the job")
+	prev := script.ResultFinished()
+	script.Run(model.ContextWithLogger(ctx, logger))
+	logger.Println("job has been finished")
 
-	return
+	// the script isn't run if it's still running from the previous start
+	r := script.ResultFinished()
+	if r.RunCounter == prev.RunCounter {
+		return
+	}
+	s.Lock()
+	h, n, st := s.history, s.notifier, s.states[name]
+	s.Unlock()
+	if st != nil {
+		st.add(j, &r)
+	}
+	if n != nil {
+		n.Notify(name, prev, r)
+	}
+	if h != nil {
+		if err := h.Add(name, r); err != nil {
+			logger.Printf("can't save the result to the history: %v\n", err)
+		}
+	}
+	s.startHooks(logger, j, prev, r, st)
 }
 
 func (s *Scheduler) addJob(j model.ScheduleJob) {
+	st := s.newJobState(j)
 	s.Lock()
 	s.jobs = append(s.jobs, j)
+	if s.states == nil {
+		s.states = make(map[string]*jobState)
+	}
+	s.states[j.Name] = st
 	s.Unlock()
 }
 
+// replacedJob is the job with its state replaced by swapJob, found is false if the job has been added
+type replacedJob struct {
+	name  string
+	job   model.ScheduleJob
+	state *jobState
+	found bool
+}
+
+// swapJob schedules the job in place of the job with the same name or adds it,
+// the cron job of the replaced job is removed first, so they are never scheduled at once,
+// the replaced job is returned to be restored, the replaced job is kept if the job can't be scheduled
+func (s *Scheduler) swapJob(j model.ScheduleJob) (prev replacedJob, err error) {
+	st := s.newJobState(j)
+	s.Lock()
+	defer s.Unlock()
+	prev.name = j.Name
+	idx := jobIndex(s.jobs, j.Name)
+	if idx >= 0 {
+		prev.job, prev.state, prev.found = s.jobs[idx], s.states[j.Name], true
+		if prev.job.CronJob != nil {
+			s.Scheduler.RemoveByReference(prev.job.CronJob)
+		}
+	}
+	if !j.Paused {
+		if j.CronJob, err = s.addCronJob(j); err != nil {
+			s.restoreJobLocked(prev)
+			return
+		}
+	}
+	if idx >= 0 {
+		s.jobs[idx] = j
+	} else {
+		s.jobs = append(s.jobs, j)
+	}
+	if s.states == nil {
+		s.states = make(map[string]*jobState)
+	}
+	s.states[j.Name] = st
+	return
+}
+
+// restoreJob returns the job replaced by swapJob back with its state or removes the added job
+func (s *Scheduler) restoreJob(prev replacedJob) {
+	s.Lock()
+	defer s.Unlock()
+	s.restoreJobLocked(prev)
+}
+
+func (s *Scheduler) restoreJobLocked(prev replacedJob) {
+	idx := jobIndex(s.jobs, prev.name)
+	if idx >= 0 && s.jobs[idx].CronJob != nil {
+		s.Scheduler.RemoveByReference(s.jobs[idx].CronJob)
+	}
+	if !prev.found {
+		if idx >= 0 {
+			s.jobs = append(s.jobs[:idx], s.jobs[idx+1:]...)
+		}
+		delete(s.states, prev.name)
+		return
+	}
+	j := prev.job
+	if j.CronJob != nil {
+		var err error
+		if j.CronJob, err = s.addCronJob(j); err != nil {
+			s.logger.Printf("[%v] can't be scheduled again: %v\n", j.Name, err)
+		}
+	}
+	if idx >= 0 {
+		s.jobs[idx] = j
+	} else {
+		s.jobs = append(s.jobs, j)
+	}
+	if s.states == nil {
+		s.states = make(map[string]*jobState)
+	}
+	s.states[prev.name] = prev.state
+}
+
 func (s *Scheduler) delJob(name string) {
 	s.Lock()
 	defer s.Unlock()
@@ -142,7 +391,9 @@ func (s *Scheduler) delJob(name string) {
 	for i, j := range s.jobs {
 		if j.Name == name {
 			idx = i
-			s.Scheduler.RemoveByReference(j.CronJob)
+			if j.CronJob != nil {
+				s.Scheduler.RemoveByReference(j.CronJob)
+			}
 			break
 		}
 	}
@@ -150,6 +401,20 @@ func (s *Scheduler) delJob(name string) {
 		return
 	}
 	s.jobs = append(s.jobs[:idx], s.jobs[idx+1:]...)
+	delete(s.states, name)
+	if s.notifier != nil {
+		s.notifier.Forget(name)
+	}
+}
+
+// forgetJob drops the notifier state of the replaced job, the new job is notified from scratch
+func (s *Scheduler) forgetJob(name string) {
+	s.Lock()
+	n := s.notifier
+	s.Unlock()
+	if n != nil {
+		n.Forget(name)
+	}
 }
 
 func (s *Scheduler) getJob(name string) (j model.ScheduleJob, err error) {
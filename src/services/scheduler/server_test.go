@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-co-op/gocron"
+)
+
+// forgetNotifier records the jobs forgotten by the scheduler
+type forgetNotifier struct {
+	forgotten map[string]bool
+}
+
+func (n *forgetNotifier) Notify(string, model.ScriptResult, model.ScriptResult) {}
+func (n *forgetNotifier) Forget(job string)                                     { n.forgotten[job] = true }
+
+func Test_ApplyJobs(t *testing.T) {
+	n := &forgetNotifier{forgotten: map[string]bool{}}
+	s := &Scheduler{
+		Scheduler: gocron.NewScheduler(time.Local),
+		logger:    model.NewChainLogger(logger, "scheduler"),
+		notifier:  n,
+	}
+	job := func(name, schedule, hash string) model.ScheduleJob {
+		return model.ScheduleJob{Name: name, ScriptFile: name + ".yml", Schedule: schedule, ScriptHash: hash, Script: &model.Script{}}
+	}
+
+	if err := s.ApplyJobs([]model.ScheduleJob{job("a", "60s", "1"), job("b", "60s", "1"), job("c", "60s", "1")}); err != nil {
+		t.Fatalf("jobs should be applied: %v", err)
+	}
+	before := map[string]model.ScheduleJob{}
+	for _, j := range s.jobs {
+		before[j.Name] = j
+	}
+
+	// a is unchanged, b has a new schedule, c is removed, d is added
+	err := s.ApplyJobs([]model.ScheduleJob{job("a", "60s", "1"), job("b", "30s", "1"), job("d", "60s", "1")})
+	if err != nil {
+		t.Fatalf("jobs should be applied: %v", err)
+	}
+	after := map[string]model.ScheduleJob{}
+	for _, j := range s.jobs {
+		after[j.Name] = j
+	}
+	if len(after) != 3 {
+		t.Fatalf("there should be 3 jobs, got %v", len(after))
+	}
+	if after["a"].Script != before["a"].Script || after["a"].CronJob != before["a"].CronJob {
+		t.Fatal("unchanged job should be kept")
+	}
+	if after["b"].Script == before["b"].Script || after["b"].Schedule != "30s" {
+		t.Fatal("changed job should be replaced")
+	}
+	if _, ok := after["c"]; ok {
+		t.Fatal("removed job should be deleted")
+	}
+	if _, ok := after["d"]; !ok {
+		t.Fatal("new job should be added")
+	}
+	if n := len(s.Scheduler.Jobs()); n != 3 {
+		t.Fatalf("there should be 3 cron jobs, got %v", n)
+	}
+	if len(n.forgotten) != 2 || !n.forgotten["b"] || !n.forgotten["c"] {
+		t.Fatalf("the notifier state of the replaced and removed jobs should be dropped, got %v", n.forgotten)
+	}
+
+	// the invalid schedule prevents the whole configuration from applying
+	err = s.ApplyJobs([]model.ScheduleJob{job("a", "60s", "2"), job("e", "wrong schedule", "1")})
+	if err == nil {
+		t.Fatal("invalid job schedule should return an error")
+	}
+	if len(s.jobs) != 3 || len(s.Scheduler.Jobs()) != 3 {
+		t.Fatal("jobs shouldn't be changed on error")
+	}
+}
+
+func Test_ApplyJobsConcurrent(t *testing.T) {
+	s := &Scheduler{
+		Scheduler: gocron.NewScheduler(time.Local),
+		logger:    model.NewChainLogger(logger, "scheduler"),
+	}
+	job := func(name, schedule string) model.ScheduleJob {
+		return model.ScheduleJob{Name: name, ScriptFile: name + ".yml", Schedule: schedule, ScriptHash: schedule, Script: &model.Script{}}
+	}
+	configs := [][]model.ScheduleJob{
+		{job("a", "60s"), job("b", "60s")},
+		{job("a", "30s"), job("c", "60s")},
+		{job("b", "30s"), job("c", "30s"), job("d", "60s")},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(jobs []model.ScheduleJob) {
+			defer wg.Done()
+			if err := s.ApplyJobs(jobs); err != nil {
+				t.Errorf("jobs should be applied: %v", err)
+			}
+		}(configs[i%len(configs)])
+	}
+	wg.Wait()
+
+	// the result is one of the configurations without duplicated or stale cron jobs
+	names := map[string]bool{}
+	for _, j := range s.jobs {
+		names[j.Name] = true
+	}
+	cronNames := map[string]int{}
+	for _, c := range s.Scheduler.Jobs() {
+		cronNames[c.GetName()]++
+	}
+	if len(names) != len(s.jobs) || len(cronNames) != len(s.jobs) || len(s.Scheduler.Jobs()) != len(s.jobs) {
+		t.Fatalf("jobs %v and cron jobs %v should match", names, cronNames)
+	}
+	for name := range cronNames {
+		if !names[name] {
+			t.Fatalf("cron job %v isn't in the jobs %v", name, names)
+		}
+	}
+}
+
+func Test_ApplyJobsRestore(t *testing.T) {
+	s := &Scheduler{
+		Scheduler: gocron.NewScheduler(time.Local),
+		logger:    model.NewChainLogger(logger, "scheduler"),
+	}
+	job := func(name, schedule string) model.ScheduleJob {
+		return model.ScheduleJob{Name: name, ScriptFile: name + ".yml", Schedule: schedule, ScriptHash: schedule, Script: &model.Script{}}
+	}
+	if err := s.ApplyJobs([]model.ScheduleJob{job("a", "60s"), job("b", "60s")}); err != nil {
+		t.Fatalf("jobs should be applied: %v", err)
+	}
+	before, _ := s.getJob("a")
+	beforeState := s.getJobState("a")
+
+	// a replaced and c added are restored as if the jobs haven't been applied
+	replaced, err := s.swapJob(job("a", "30s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err := s.swapJob(job("c", "60s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.restoreJob(added)
+	s.restoreJob(replaced)
+
+	after, err := s.getJob("a")
+	if err != nil || after.Script != before.Script || after.Schedule != "60s" || s.getJobState("a") != beforeState {
+		t.Fatalf("replaced job should be restored with its state, got %+v", after)
+	}
+	if _, err = s.getJob("c"); err == nil || s.getJobState("c") != nil {
+		t.Fatal("added job should be removed")
+	}
+	cronJobs := s.Scheduler.Jobs()
+	if len(s.jobs) != 2 || len(cronJobs) != 2 {
+		t.Fatalf("jobs = %v, cron jobs = %v, want 2", len(s.jobs), len(cronJobs))
+	}
+	for _, c := range cronJobs {
+		if c.GetName() == "a" && c != after.CronJob {
+			t.Fatal("restored job should have its cron job")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"sync"
+)
+
+// taskStability keeps the latest results of the task
+type taskStability struct {
+	results   []bool // the oldest result first
+	failures  int    // failed results in a row
+	successes int    // successful results in a row
+	state     model.TaskStability
+}
+
+// stabilityTracker computes the stable state of the job tasks, skipped tasks don't change the state
+type stabilityTracker struct {
+	config model.Stability
+	tasks  map[string]*taskStability
+	sync.Mutex
+}
+
+func newStabilityTracker(config *model.Stability) *stabilityTracker {
+	return &stabilityTracker{config: config.WithDefaults(), tasks: make(map[string]*taskStability)}
+}
+
+// add counts the task results of the run and sets their stable state
+func (t *stabilityTracker) add(r *model.ScriptResult) {
+	t.Lock()
+	for _, task := range r.Tasks {
+		if task.Status != string(model.EStatusSkipped) {
+			t.addTaskResult(task.Name, task.Success)
+		}
+	}
+	t.Unlock()
+	t.apply(r)
+}
+
+func (t *stabilityTracker) addTaskResult(name string, success bool) {
+	ts, ok := t.tasks[name]
+	if !ok {
+		// the first result is the stable state
+		ts = &taskStability{state: model.TaskStability{StableSuccess: success}}
+		t.tasks[name] = ts
+	}
+	ts.results = append(ts.results, success)
+	if len(ts.results) > t.config.Window {
+		ts.results = ts.results[len(ts.results)-t.config.Window:]
+	}
+	if success {
+		ts.successes++
+		ts.failures = 0
+	} else {
+		ts.failures++
+		ts.successes = 0
+	}
+	if ts.state.StableSuccess && ts.failures >= t.config.FailThreshold {
+		ts.state.StableSuccess = false
+	} else if !ts.state.StableSuccess && ts.successes >= t.config.RecoverThreshold {
+		ts.state.StableSuccess = true
+	}
+
+	changes := 0
+	for i := 1; i < len(ts.results); i++ {
+		if ts.results[i] != ts.results[i-1] {
+			changes++
+		}
+	}
+	ts.state.Flapping = changes >= t.config.FlapThreshold
+}
+
+// apply sets the current stable state of the tasks of the result
+func (t *stabilityTracker) apply(r *model.ScriptResult) {
+	t.Lock()
+	defer t.Unlock()
+	for i, task := range r.Tasks {
+		if ts, ok := t.tasks[task.Name]; ok {
+			state := ts.state
+			r.Tasks[i].TaskStability = &state
+		}
+	}
+}
+
+// state returns the stable state of the task, false if the task has no results yet
+func (t *stabilityTracker) state(task string) (state model.TaskStability, ok bool) {
+	if t == nil {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	ts, ok := t.tasks[task]
+	if ok {
+		state = ts.state
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"testing"
+)
+
+func Test_StabilityTracker(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *model.Stability
+		results      []bool
+		wantStable   bool
+		wantFlapping bool
+	}{
+		{"default follows the result", nil, []bool{true, false}, false, false},
+		{"first failure is stable", &model.Stability{FailThreshold: 3}, []bool{false}, false, false},
+		{"failures below the threshold", &model.Stability{FailThreshold: 3}, []bool{true, false, false}, true, false},
+		{"failures reach the threshold", &model.Stability{FailThreshold: 3}, []bool{true, false, false, false}, false, false},
+		{"interrupted failures", &model.Stability{FailThreshold: 2}, []bool{true, false, true, false, true}, true, true},
+		{"recovery below the threshold", &model.Stability{RecoverThreshold: 2}, []bool{false, true}, false, false},
+		{"recovery reaches the threshold", &model.Stability{RecoverThreshold: 2}, []bool{false, true, true}, true, false},
+		{"changes below the flap threshold", &model.Stability{FlapThreshold: 3}, []bool{true, false, true}, true, false},
+		{"flapping", &model.Stability{FlapThreshold: 3}, []bool{true, false, true, false}, false, true},
+		{"flapping is out of the window", &model.Stability{Window: 4, FlapThreshold: 3}, []bool{true, false, true, false, false, false, false}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newStabilityTracker(tt.config)
+			var r model.ScriptResult
+			for _, success := range tt.results {
+				r = taskResults(success)
+				st.add(&r)
+			}
+			got := r.Tasks[0].TaskStability
+			if got == nil || got.StableSuccess != tt.wantStable || got.Flapping != tt.wantFlapping {
+				t.Errorf("state = %+v, want stable %v, flapping %v", got, tt.wantStable, tt.wantFlapping)
+			}
+			if state, _ := st.state("task"); state != *got {
+				t.Errorf("the current state %+v differs from the result one %+v", state, *got)
+			}
+		})
+	}
+}
+
+func Test_StabilitySkipped(t *testing.T) {
+	st := newStabilityTracker(nil)
+	r := taskResults(true)
+	st.add(&r)
+	r = taskResults(false)
+	r.Tasks[0].Status = string(model.EStatusSkipped)
+	st.add(&r)
+	if state := r.Tasks[0].TaskStability; state == nil || !state.StableSuccess {
+		t.Errorf("a skipped task shouldn't change the state, got %+v", state)
+	}
+
+	r = model.ScriptResult{Tasks: []model.TaskResult{{Name: "other", Status: string(model.EStatusSkipped)}}}
+	st.add(&r)
+	if r.Tasks[0].TaskStability != nil {
+		t.Errorf("a task without results shouldn't have the state, got %+v", r.Tasks[0].TaskStability)
+	}
+}
+
+func Test_StabilityValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *model.Stability
+		wantErr bool
+	}{
+		{"default", nil, false},
+		{"custom", &model.Stability{FailThreshold: 3, RecoverThreshold: 2, Window: 20, FlapThreshold: 6}, false},
+		{"negative", &model.Stability{FailThreshold: -1}, true},
+		{"flap threshold out of the window", &model.Stability{Window: 5, FlapThreshold: 5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"sync"
+	"time"
+)
+
+// entityTimes keeps the times of the script or the task runs
+type entityTimes struct {
+	times   model.StateTimes
+	success bool
+}
+
+func (e *entityTimes) add(at time.Time, success bool) {
+	if e.times.LastRunAt == nil || e.success != success {
+		e.times.LastChangeAt = &at
+	}
+	e.times.LastRunAt = &at
+	if success {
+		e.times.LastSuccessAt = &at
+	}
+	e.success = success
+}
+
+// copy returns the times which aren't changed by the next runs
+func (e *entityTimes) copy() *model.StateTimes {
+	times := e.times
+	return &times
+}
+
+// timesTracker keeps the times of the last runs of the job script and tasks by their start time,
+// skipped tasks aren't counted
+type timesTracker struct {
+	script *entityTimes
+	tasks  map[string]*entityTimes
+	sync.Mutex
+}
+
+func newTimesTracker() *timesTracker {
+	return &timesTracker{tasks: make(map[string]*entityTimes)}
+}
+
+// add counts the run and sets the times of the result
+func (t *timesTracker) add(r *model.ScriptResult) {
+	t.Lock()
+	if t.script == nil {
+		t.script = &entityTimes{}
+	}
+	t.script.add(r.StartedAt, r.Success)
+	for _, task := range r.Tasks {
+		if task.Status == string(model.EStatusSkipped) {
+			continue
+		}
+		e, ok := t.tasks[task.Name]
+		if !ok {
+			e = &entityTimes{}
+			t.tasks[task.Name] = e
+		}
+		at := task.StartedAt
+		if at.IsZero() {
+			at = r.StartedAt
+		}
+		e.add(at, task.Success)
+	}
+	t.Unlock()
+	t.apply(r)
+}
+
+// restore counts the results of the job saved in the history
+func (t *timesTracker) restore(h history.Store, job string) error {
+	if h == nil {
+		return nil
+	}
+	results, err := h.Query(history.Query{Job: job})
+	if err != nil {
+		return err
+	}
+	for i := range results {
+		t.add(&results[i])
+	}
+	return nil
+}
+
+// apply sets the current times of the script and the tasks of the result
+func (t *timesTracker) apply(r *model.ScriptResult) {
+	t.Lock()
+	defer t.Unlock()
+	if t.script != nil {
+		r.StateTimes = t.script.copy()
+	}
+	for i, task := range r.Tasks {
+		if e, ok := t.tasks[task.Name]; ok {
+			r.Tasks[i].StateTimes = e.copy()
+		}
+	}
+}
+
+// scriptTimes returns the times of the script runs, nil if the script hasn't been run
+func (t *timesTracker) scriptTimes() *model.StateTimes {
+	if t == nil {
+		return nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	if t.script == nil {
+		return nil
+	}
+	return t.script.copy()
+}
+
+// taskTimes returns the times of the task runs, nil if the task hasn't been run
+func (t *timesTracker) taskTimes(task string) *model.StateTimes {
+	if t == nil {
+		return nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	if e, ok := t.tasks[task]; ok {
+		return e.copy()
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"boogieman/src/model"
+	"boogieman/src/services/history"
+	"testing"
+	"time"
+)
+
+func timesString(times *model.StateTimes, base time.Time) (s [3]time.Duration) {
+	for i, t := range []*time.Time{times.LastRunAt, times.LastSuccessAt, times.LastChangeAt} {
+		if t == nil {
+			s[i] = -1
+		} else {
+			s[i] = t.Sub(base)
+		}
+	}
+	return
+}
+
+func Test_timesTracker(t *testing.T) {
+	base := time.Date(2023, 12, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	tests := []struct {
+		name    string
+		results []bool
+		want    [3]time.Duration // run, success, change
+	}{
+		{"first failure", []bool{false}, [3]time.Duration{0, -1, 0}},
+		{"first success", []bool{true}, [3]time.Duration{0, 0, 0}},
+		{"still succeeded", []bool{true, true, true}, [3]time.Duration{2 * time.Minute, 2 * time.Minute, 0}},
+		{"failure after success", []bool{true, true, false, false}, [3]time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute}},
+		{"recovery", []bool{false, true}, [3]time.Duration{time.Minute, time.Minute, time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTimesTracker()
+			var r model.ScriptResult
+			for i, success := range tt.results {
+				r = runResult(at(i), success)
+				r.Success = success
+				tr.add(&r)
+			}
+			if got := timesString(r.StateTimes, base); got != tt.want {
+				t.Errorf("script times = %v, want %v", got, tt.want)
+			}
+			if got := timesString(r.Tasks[0].StateTimes, base); got != tt.want {
+				t.Errorf("task times = %v, want %v", got, tt.want)
+			}
+			if got := timesString(tr.taskTimes("task"), base); got != tt.want {
+				t.Errorf("current task times = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_timesTrackerSkipped(t *testing.T) {
+	base := time.Now()
+	tr := newTimesTracker()
+	r := runResult(base, true)
+	r.Success = true
+	tr.add(&r)
+	r = runResult(base.Add(time.Minute), false)
+	r.Tasks[0].Status = string(model.EStatusSkipped)
+	tr.add(&r)
+	if got := timesString(r.Tasks[0].StateTimes, base); got != [3]time.Duration{0, 0, 0} {
+		t.Errorf("a skipped task shouldn't be counted, got %v", got)
+	}
+	if got := timesString(r.StateTimes, base); got != [3]time.Duration{time.Minute, 0, time.Minute} {
+		t.Errorf("the script run should be counted, got %v", got)
+	}
+}
+
+func Test_timesTrackerRestore(t *testing.T) {
+	h := history.NewMemory(history.Retention{})
+	base := time.Now().Add(-time.Hour)
+	for i, success := range []bool{false, true, true} {
+		r := runResult(base.Add(time.Duration(i)*time.Minute), success)
+		r.Success = success
+		_ = h.Add("job", r)
+	}
+
+	s := &Scheduler{logger: model.NewChainLogger(logger, "scheduler")}
+	s.SetHistory(h)
+	s.addJob(model.ScheduleJob{Name: "job", Script: &model.Script{}})
+	want := [3]time.Duration{2 * time.Minute, 2 * time.Minute, time.Minute}
+	if got := timesString(s.getJobState("job").times.scriptTimes(), base); got != want {
+		t.Errorf("the script times should be restored from the history, got %v, want %v", got, want)
+	}
+	if got := timesString(s.getJobState("job").times.taskTimes("task"), base); got != want {
+		t.Errorf("the task times should be restored from the history, got %v, want %v", got, want)
+	}
+}